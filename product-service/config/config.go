@@ -185,6 +185,10 @@ func setFieldValue(field reflect.Value, value string) {
 		}
 	case reflect.String:
 		field.SetString(value)
+	case reflect.Bool:
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			field.SetBool(boolValue)
+		}
 	}
 }
 
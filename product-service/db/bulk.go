@@ -0,0 +1,151 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultBatchSize is the number of records CreateMany and UpsertMany send
+// per statement when BulkOptions.BatchSize is not set.
+const DefaultBatchSize = 100
+
+// ErrNotRun is the error of the items an ordered bulk operation did not run
+// after one failed.
+var ErrNotRun = errors.New("db: not run after a failed write")
+
+type BulkOptions struct {
+	// BatchSize is the number of records per statement, default
+	// DefaultBatchSize.
+	BatchSize int
+	// Ordered stops at the first failed item, the items after it are not run
+	// and fail with ErrNotRun. Unordered runs every item, Mongo may run them
+	// in any order. In both modes the items written before a failure are
+	// kept.
+	Ordered bool
+	// Keys are the fields Upsert matches the existing records on, default
+	// the id. SQL databases need a unique index on them, MySQL matches on any
+	// unique index.
+	Keys []string
+}
+
+// bulkOptions merges opts, the last one setting a field wins.
+func bulkOptions(opts []BulkOptions) BulkOptions {
+	o := BulkOptions{BatchSize: DefaultBatchSize, Keys: []string{"id"}}
+	for _, opt := range opts {
+		if opt.BatchSize > 0 {
+			o.BatchSize = opt.BatchSize
+		}
+		if opt.Ordered {
+			o.Ordered = true
+		}
+		if len(opt.Keys) > 0 {
+			o.Keys = opt.Keys
+		}
+	}
+	return o
+}
+
+type WriteKind int
+
+const (
+	InsertWrite WriteKind = iota
+	UpdateWrite
+	UpsertWrite
+	DeleteWrite
+)
+
+// WriteOp is a write of BulkWrite. Insert and Upsert write Model, Update sets
+// the fields of Model on the records matching Filter like DataStore.Update,
+// Delete deletes the records matching Filter like DataStore.Delete.
+type WriteOp[T any] struct {
+	Kind   WriteKind
+	Model  T
+	Filter interface{}
+}
+
+// ItemResult is the outcome of an item of a bulk operation, Err is nil when
+// it was written.
+type ItemResult struct {
+	Err error
+}
+
+// BulkResult holds the outcome of every item of a bulk operation, in the
+// order of the items, and the number of records written.
+//
+// Matched and Modified count the updated records, the soft deleted ones
+// included, SQL databases report Matched only. Upserted counts the records an
+// upsert inserted, Matched those it updated, SQL databases do not tell them
+// apart and count both in Upserted.
+type BulkResult struct {
+	Items    []ItemResult
+	Inserted int64
+	Matched  int64
+	Modified int64
+	Upserted int64
+	Deleted  int64
+}
+
+// Failed lists the index of the failed items.
+func (r BulkResult) Failed() []int {
+	var failed []int
+	for i, item := range r.Items {
+		if item.Err != nil {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
+
+// BulkError is the error of a bulk operation some items of which failed,
+// Err is the error of the first one.
+type BulkError struct {
+	Failed int
+	Err    error
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("%d bulk writes failed: %v", e.Failed, e.Err)
+}
+
+func (e *BulkError) Unwrap() error {
+	return e.Err
+}
+
+// skip fails with ErrNotRun the items from index on that have no outcome.
+func (r *BulkResult) skip(from int) {
+	for i := from; i < len(r.Items); i++ {
+		if r.Items[i].Err == nil {
+			r.Items[i].Err = ErrNotRun
+		}
+	}
+}
+
+// result is the Result of the bulk operation, Count is the number of items
+// written.
+func (r BulkResult) result(raw string) Result[BulkResult] {
+	result := Result[BulkResult]{Data: r, Raw: raw}
+	failed := r.Failed()
+	result.Count = int64(len(r.Items) - len(failed))
+	if len(failed) > 0 {
+		result.Err = &BulkError{Failed: len(failed), Err: r.Items[failed[0]].Err}
+	}
+	return result
+}
+
+// batches calls fn with the bounds of the batches of n items of size.
+func batches(n, size int, fn func(start, end int) (stop bool)) {
+	for start := 0; start < n; start += size {
+		if fn(start, min(start+size, n)) {
+			return
+		}
+	}
+}
+
+// span lists the indexes from start to end.
+func span(start, end int) []int {
+	index := make([]int, end-start)
+	for i := range index {
+		index[i] = start + i
+	}
+	return index
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bulkItem struct {
+	ID        string     `gorm:"primaryKey" bson:"_id"`
+	Code      string     `gorm:"uniqueIndex" bson:"code"`
+	Name      string     `bson:"name"`
+	DeletedAt *time.Time `bson:"deleted_at,omitempty"`
+}
+
+func bulkItems(ids ...string) []bulkItem {
+	items := make([]bulkItem, len(ids))
+	for i, id := range ids {
+		items[i] = bulkItem{ID: id, Code: "code " + id, Name: "item " + id}
+	}
+	return items
+}
+
+func names(t *testing.T, store DataStore[bulkItem]) map[string]string {
+	found := store.Find(context.Background(), FindOption{WithDeleted: true})
+	require.NoError(t, found.Err)
+	names := map[string]string{}
+	for _, item := range found.Data {
+		names[item.ID] = item.Name
+	}
+	return names
+}
+
+// testBulk runs the bulk write cases against store.
+func testBulk(t *testing.T, store DataStore[bulkItem]) {
+	ctx := context.Background()
+
+	// unordered, the duplicate of 1 fails alone
+	created := store.CreateMany(ctx, bulkItems("1", "1", "2", "3", "4"), BulkOptions{BatchSize: 2})
+	var bulkErr *BulkError
+	require.ErrorAs(t, created.Err, &bulkErr, created.Raw)
+	assert.Equal(t, 1, bulkErr.Failed)
+	assert.Equal(t, []int{1}, created.Data.Failed())
+	assert.Equal(t, int64(4), created.Count)
+	assert.Equal(t, int64(4), created.Data.Inserted)
+	assert.Equal(t, int64(4), store.Count(ctx).Count)
+
+	// ordered, the items after the duplicate of 2 are not run
+	created = store.CreateMany(ctx, bulkItems("5", "2", "6"), BulkOptions{Ordered: true})
+	require.Error(t, created.Err)
+	assert.NoError(t, created.Data.Items[0].Err)
+	assert.Error(t, created.Data.Items[1].Err)
+	assert.ErrorIs(t, created.Data.Items[2].Err, ErrNotRun)
+	assert.Equal(t, int64(1), created.Count)
+	assert.Equal(t, int64(5), store.Count(ctx).Count)
+
+	// by code, the existing record keeps its id
+	upserted := store.Upsert(ctx, bulkItem{ID: "x", Code: "code 1", Name: "one"}, "code")
+	require.NoError(t, upserted.Err, upserted.Raw)
+	assert.Equal(t, bulkItem{ID: "1", Code: "code 1", Name: "one"}, upserted.Data)
+	upserted = store.Upsert(ctx, bulkItem{ID: "7", Code: "code 7", Name: "seven"})
+	require.NoError(t, upserted.Err, upserted.Raw)
+	assert.Equal(t, "7", upserted.Data.ID)
+
+	many := store.UpsertMany(ctx, []bulkItem{{ID: "2", Code: "code 2", Name: "two"}, {ID: "8", Code: "code 8", Name: "eight"}})
+	require.NoError(t, many.Err, many.Raw)
+	assert.Equal(t, int64(2), many.Count)
+	assert.Equal(t, int64(2), many.Data.Upserted+many.Data.Matched)
+
+	ops := []WriteOp[bulkItem]{
+		{Kind: InsertWrite, Model: bulkItems("9")[0]},
+		{Kind: UpdateWrite, Filter: map[string]interface{}{"id": "5"}, Model: bulkItem{Name: "five"}},
+		{Kind: DeleteWrite, Filter: Filter{Key: "id", Value: "3"}},
+		{Kind: InsertWrite, Model: bulkItems("4")[0]},
+		{Kind: UpsertWrite, Model: bulkItem{ID: "4", Code: "code 4", Name: "four"}},
+		{Kind: DeleteWrite},
+	}
+	written := store.BulkWrite(ctx, ops)
+	require.ErrorAs(t, written.Err, &bulkErr, written.Raw)
+	assert.Equal(t, []int{3, 5}, written.Data.Failed())
+	assert.ErrorIs(t, written.Data.Items[5].Err, ErrEmptyFilter)
+	assert.Equal(t, int64(1), written.Data.Inserted)
+	assert.Equal(t, int64(4), written.Count)
+
+	// 3 is soft deleted
+	assert.Equal(t, int64(7), store.Count(ctx).Count)
+	assert.Equal(t, map[string]string{
+		"1": "one", "2": "two", "3": "item 3", "4": "four", "5": "five",
+		"7": "seven", "8": "eight", "9": "item 9",
+	}, names(t, store))
+
+	ordered := store.BulkWrite(ctx, []WriteOp[bulkItem]{
+		{Kind: DeleteWrite, Filter: Filter{Key: "id", Value: "9"}},
+		{Kind: InsertWrite, Model: bulkItems("1")[0]},
+		{Kind: DeleteWrite, Filter: Filter{Key: "id", Value: "8"}},
+	}, BulkOptions{Ordered: true})
+	require.Error(t, ordered.Err)
+	assert.Equal(t, []int{1, 2}, ordered.Data.Failed())
+	assert.ErrorIs(t, ordered.Data.Items[2].Err, ErrNotRun)
+	assert.Equal(t, int64(6), store.Count(ctx).Count)
+}
+
+func TestBulkGorm(t *testing.T) {
+	testBulk(t, newSQLiteStore[bulkItem](t))
+}
+
+func TestBulkMongo(t *testing.T) {
+	testBulk(t, newMongoStore[bulkItem](t))
+}
+
+func TestBulkOptions(t *testing.T) {
+	assert.Equal(t, BulkOptions{BatchSize: DefaultBatchSize, Keys: []string{"id"}}, bulkOptions(nil))
+	assert.Equal(t, BulkOptions{BatchSize: 10, Ordered: true, Keys: []string{"code"}},
+		bulkOptions([]BulkOptions{{BatchSize: 5, Ordered: true}, {BatchSize: 10, Keys: []string{"code"}}}))
+}
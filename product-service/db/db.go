@@ -1,24 +1,64 @@
 package db
 
+import (
+	"context"
+	"sort"
+)
+
+// DataStore methods run with ctx, cancelling ctx or reaching its deadline
+// aborts the query in the driver.
 type DataStore[T any] interface {
-	Find(findOption ...FindOption) Result[[]T]
-	Count(findOption ...FindOption) Result[int64]
-	Create(model T) Result[T]
-	FindOne(findOption ...FindOption) Result[T]
-	Update(filter interface{}, update T) error
-	FindAndCount(findOption ...FindOption) Result[[]T]
+	Find(ctx context.Context, findOption ...FindOption) Result[[]T]
+	Count(ctx context.Context, findOption ...FindOption) Result[int64]
+	Create(ctx context.Context, model T) Result[T]
+	FindOne(ctx context.Context, findOption ...FindOption) Result[T]
+	// Update writes update to the records matching filter, Count is the
+	// number of matched records. An empty filter is refused with
+	// ErrEmptyFilter.
+	Update(ctx context.Context, filter interface{}, update T) Result[int64]
+	FindAndCount(ctx context.Context, findOption ...FindOption) Result[[]T]
+	// Delete deletes the records matching filter, Count is the number of
+	// deleted records. The records of a T with a DeletedAt field are soft
+	// deleted: DeletedAt is set and the queries skip them.
+	Delete(ctx context.Context, filter interface{}) Result[int64]
+	// Restore undoes the soft delete of the records matching filter.
+	Restore(ctx context.Context, filter interface{}) Result[int64]
+	// HardDelete removes the records matching filter, soft deleted or not.
+	HardDelete(ctx context.Context, filter interface{}) Result[int64]
+	// CreateMany creates models in batches, Count is the number of records
+	// created and Data the outcome of every model.
+	CreateMany(ctx context.Context, models []T, opts ...BulkOptions) Result[BulkResult]
+	// Upsert creates model, or updates the record with the same keys, the id
+	// by default, Data is the record written. The DeletedAt of an existing
+	// record is left as is.
+	Upsert(ctx context.Context, model T, keys ...string) Result[T]
+	// UpsertMany upserts models in batches, on the BulkOptions.Keys.
+	UpsertMany(ctx context.Context, models []T, opts ...BulkOptions) Result[BulkResult]
+	// BulkWrite runs ops, Count is the number of ops that succeeded and Data
+	// the outcome of every op.
+	BulkWrite(ctx context.Context, ops []WriteOp[T], opts ...BulkOptions) Result[BulkResult]
 }
 
 type Result[T any] struct {
-	Err   error
+	Err error
+	// Count is the total of the matching records for Count and FindAndCount,
+	// not only of the page.
 	Count int64
 	Data  T
-	Raw   string
+	// Raw is the query as the shell of the database would show it, for
+	// logging only. It is never run and its values are not escaped for it.
+	Raw string
+	// Page and Limit are the page Find and FindAndCount returned.
+	Page  int
+	Limit int
 }
 
-type Filter struct {
-	Key   string
-	Value interface{}
+// Pages is the number of pages of Limit records holding Count records.
+func (r Result[T]) Pages() int {
+	if r.Limit <= 0 {
+		return 0
+	}
+	return int((r.Count + int64(r.Limit) - 1) / int64(r.Limit))
 }
 
 // enum for sort direction
@@ -29,10 +69,67 @@ const (
 	DESC
 )
 
+const (
+	// DefaultLimit is the page size of Find when FindOption.Limit is not set.
+	DefaultLimit = 10
+	// MaxLimit caps FindOption.Limit.
+	MaxLimit = 100
+)
+
 type FindOption struct {
-	Filter     []Filter
+	Filter []Filter
+	// Page starts at 1, Limit is DefaultLimit when not set and at most MaxLimit.
 	Page       int
 	Limit      int
 	Projection string
 	Sort       map[string]SortDirection
+	// Order lists the Sort keys by priority, the keys it does not list
+	// follow in name order.
+	Order []string
+	// WithDeleted finds the soft deleted records too.
+	WithDeleted bool
+}
+
+// paginate is the page, limit and number of records to skip of findOption,
+// the last option setting them wins.
+func paginate(findOption []FindOption) (page, limit, skip int) {
+	page, limit = 1, DefaultLimit
+	for _, option := range findOption {
+		if option.Page > 0 {
+			page = option.Page
+		}
+		if option.Limit > 0 {
+			limit = min(option.Limit, MaxLimit)
+		}
+	}
+	return page, limit, (page - 1) * limit
+}
+
+// sortKeys lists the sort keys of findOption by the priority of their Order,
+// then in name order, since the order of a map is random.
+func sortKeys(findOption []FindOption) (keys []string, directions map[string]SortDirection) {
+	directions = map[string]SortDirection{}
+	for _, option := range findOption {
+		for key, direction := range option.Sort {
+			directions[key] = direction
+		}
+	}
+
+	listed := map[string]bool{}
+	for _, option := range findOption {
+		for _, key := range option.Order {
+			if _, ok := directions[key]; ok && !listed[key] {
+				listed[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	var rest []string
+	for key := range directions {
+		if !listed[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...), directions
 }
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+)
+
+// Operator compares the field of a Filter with its Value.
+type Operator string
+
+const (
+	Eq  Operator = "eq"
+	Ne  Operator = "ne"
+	Gt  Operator = "gt"
+	Gte Operator = "gte"
+	Lt  Operator = "lt"
+	Lte Operator = "lte"
+	// In and Nin take a slice of values.
+	In  Operator = "in"
+	Nin Operator = "nin"
+	// Like takes a SQL pattern, % matching any text and _ one character,
+	// case insensitive. A \ before %, _ or \ matches it as is.
+	Like Operator = "like"
+	// Regex takes a regular expression, case sensitive. SQLite needs a
+	// REGEXP function.
+	Regex Operator = "regex"
+	// Exists takes true to match a set, non null, field and false to match a
+	// missing or null one.
+	Exists Operator = "exists"
+)
+
+// Filter matches the records whose Key field compares to Value with
+// Operator, Eq when not set. A nil Value matches a null field with Eq and a
+// non null one with Ne, and Ne and Nin match the null fields too, like Mongo
+// does.
+//
+// A Filter with Or matches the records matching one of its filters, with And
+// those matching them all, its Key is then ignored. The filters of a
+// FindOption are and-ed.
+//
+//	db.Filter{Or: []db.Filter{
+//		{Key: "price", Operator: db.Lt, Value: 10},
+//		{Key: "name", Operator: db.Like, Value: "%sale%"},
+//	}}
+type Filter struct {
+	Key      string
+	Operator Operator
+	Value    interface{}
+	Or       []Filter
+	And      []Filter
+}
+
+// FilterError reports a filter, or a sort or projected field, the stores
+// cannot translate.
+type FilterError struct {
+	Key      string
+	Operator Operator
+	Reason   string
+}
+
+func (e *FilterError) Error() string {
+	if e.Key == "" {
+		return "invalid filter: " + e.Reason
+	}
+	if e.Operator == "" {
+		return fmt.Sprintf("invalid filter %s: %s", e.Key, e.Reason)
+	}
+	return fmt.Sprintf("invalid filter %s %s: %s", e.Key, e.Operator, e.Reason)
+}
+
+// StatusCode is 400, a filter usually comes from the query of the request.
+func (e *FilterError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// operator is the Operator of f with its Value checked.
+func (f Filter) operator() (Operator, error) {
+	op := f.Operator
+	if op == "" {
+		op = Eq
+	}
+	if f.Key == "" {
+		return op, &FilterError{Operator: op, Reason: "missing key"}
+	}
+
+	switch op {
+	case Eq, Ne, Gt, Gte, Lt, Lte:
+	case In, Nin:
+		if v := reflect.ValueOf(f.Value); v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return op, &FilterError{Key: f.Key, Operator: op, Reason: "value is not a list"}
+		}
+	case Like, Regex:
+		if _, ok := f.Value.(string); !ok {
+			return op, &FilterError{Key: f.Key, Operator: op, Reason: "value is not a string"}
+		}
+	case Exists:
+		if _, ok := f.Value.(bool); !ok {
+			return op, &FilterError{Key: f.Key, Operator: op, Reason: "value is not a boolean"}
+		}
+	default:
+		return op, &FilterError{Key: f.Key, Operator: op, Reason: "unknown operator"}
+	}
+	return op, nil
+}
+
+// values lists the Value of an In or Nin filter.
+func (f Filter) values() []interface{} {
+	v := reflect.ValueOf(f.Value)
+	values := make([]interface{}, v.Len())
+	for i := range values {
+		values[i] = v.Index(i).Interface()
+	}
+	return values
+}
+
+// filters lists the filters of findOption.
+func filters(findOption []FindOption) []Filter {
+	var all []Filter
+	for _, option := range findOption {
+		all = append(all, option.Filter...)
+	}
+	return all
+}
+
+// toFilters converts the filter of Update, which is nil, a FindOption, a
+// []Filter, a Filter or a map of field to value.
+func toFilters(filter interface{}) ([]Filter, error) {
+	switch f := filter.(type) {
+	case nil:
+		return nil, nil
+	case FindOption:
+		return f.Filter, nil
+	case []Filter:
+		return f, nil
+	case Filter:
+		return []Filter{f}, nil
+	}
+
+	// any map of string, e.g. a bson.M
+	v := reflect.ValueOf(filter)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("unsupported filter %T", filter)
+	}
+	keys := make([]string, 0, v.Len())
+	for _, key := range v.MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+
+	all := make([]Filter, len(keys))
+	for i, key := range keys {
+		all[i] = Filter{Key: key, Value: v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())).Interface()}
+	}
+	return all, nil
+}
@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"gorm.io/gorm/logger"
+)
+
+type filterItem struct {
+	ID       string  `gorm:"primaryKey" bson:"_id"`
+	Name     string  `bson:"name"`
+	Price    float64 `bson:"price"`
+	Quantity int     `bson:"quantity"`
+	Note     *string `bson:"note,omitempty"`
+}
+
+func note(s string) *string { return &s }
+
+var filterItems = []filterItem{
+	{ID: "1", Name: "Apple iPhone", Price: 999, Quantity: 5, Note: note("new")},
+	{ID: "2", Name: "apple watch", Price: 399, Quantity: 0},
+	{ID: "3", Name: "Banana", Price: 1.5, Quantity: 100, Note: note("fruit")},
+	{ID: "4", Name: "Cherry", Price: 10, Quantity: 20},
+}
+
+var filterCases = []struct {
+	name    string
+	filters []Filter
+	want    []string
+}{
+	{"eq", []Filter{{Key: "name", Value: "Banana"}}, []string{"3"}},
+	{"eq id", []Filter{{Key: "id", Operator: Eq, Value: "2"}}, []string{"2"}},
+	{"ne", []Filter{{Key: "quantity", Operator: Ne, Value: 0}}, []string{"1", "3", "4"}},
+	{"ne null field", []Filter{{Key: "note", Operator: Ne, Value: "new"}}, []string{"2", "3", "4"}},
+	{"gt", []Filter{{Key: "price", Operator: Gt, Value: 10}}, []string{"1", "2"}},
+	{"gte", []Filter{{Key: "price", Operator: Gte, Value: 10}}, []string{"1", "2", "4"}},
+	{"lt", []Filter{{Key: "price", Operator: Lt, Value: 10}}, []string{"3"}},
+	{"lte", []Filter{{Key: "price", Operator: Lte, Value: 10}}, []string{"3", "4"}},
+	{"range", []Filter{
+		{Key: "price", Operator: Gte, Value: 10},
+		{Key: "price", Operator: Lt, Value: 500},
+	}, []string{"2", "4"}},
+	{"in", []Filter{{Key: "id", Operator: In, Value: []string{"1", "3"}}}, []string{"1", "3"}},
+	{"in empty", []Filter{{Key: "id", Operator: In, Value: []string{}}}, nil},
+	{"nin", []Filter{{Key: "id", Operator: Nin, Value: []string{"1", "3"}}}, []string{"2", "4"}},
+	{"nin empty", []Filter{{Key: "id", Operator: Nin, Value: []string{}}}, []string{"1", "2", "3", "4"}},
+	{"like prefix", []Filter{{Key: "name", Operator: Like, Value: "apple%"}}, []string{"1", "2"}},
+	{"like contains", []Filter{{Key: "name", Operator: Like, Value: "%an%"}}, []string{"3"}},
+	{"like one", []Filter{{Key: "name", Operator: Like, Value: "cherr_"}}, []string{"4"}},
+	{"like literal", []Filter{{Key: "name", Operator: Like, Value: `cherr\_`}}, nil},
+	{"like escaped", []Filter{{Key: "name", Operator: Like, Value: `apple\ watch`}}, []string{"2"}},
+	{"regex", []Filter{{Key: "name", Operator: Regex, Value: "^[A-C]"}}, []string{"1", "3", "4"}},
+	{"exists", []Filter{{Key: "note", Operator: Exists, Value: true}}, []string{"1", "3"}},
+	{"not exists", []Filter{{Key: "note", Operator: Exists, Value: false}}, []string{"2", "4"}},
+	{"or", []Filter{{Or: []Filter{
+		{Key: "price", Operator: Lt, Value: 5},
+		{Key: "quantity", Value: 0},
+	}}}, []string{"2", "3"}},
+	{"and or", []Filter{
+		{Key: "quantity", Operator: Gt, Value: 0},
+		{Or: []Filter{
+			{Key: "price", Operator: Lt, Value: 5},
+			{Key: "name", Operator: Regex, Value: "^Ch"},
+		}},
+	}, []string{"3", "4"}},
+	{"or and", []Filter{{Or: []Filter{
+		{And: []Filter{
+			{Key: "name", Operator: Like, Value: "apple%"},
+			{Key: "quantity", Value: 0},
+		}},
+		{Key: "id", Value: "4"},
+	}}}, []string{"2", "4"}},
+}
+
+// testFilters runs the filter cases against store, seeded with filterItems.
+func testFilters(t *testing.T, store DataStore[filterItem]) {
+	ctx := context.Background()
+	for _, item := range filterItems {
+		require.NoError(t, store.Create(ctx, item).Err)
+	}
+
+	for _, tc := range filterCases {
+		t.Run(tc.name, func(t *testing.T) {
+			option := FindOption{Filter: tc.filters, Limit: MaxLimit}
+			result := store.Find(ctx, option)
+			require.NoError(t, result.Err, result.Raw)
+
+			var ids []string
+			for _, item := range result.Data {
+				ids = append(ids, item.ID)
+			}
+			sort.Strings(ids)
+			assert.Equal(t, tc.want, ids, result.Raw)
+
+			count := store.Count(ctx, option)
+			require.NoError(t, count.Err, count.Raw)
+			assert.Equal(t, int64(len(tc.want)), count.Count, count.Raw)
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, f := range []Filter{
+			{Key: "price", Operator: "between", Value: 1},
+			{Key: "id", Operator: In, Value: "1"},
+			{Key: "name", Operator: Like, Value: 1},
+			{Key: "note", Operator: Exists, Value: "yes"},
+			{Operator: Eq, Value: 1},
+		} {
+			result := store.Find(ctx, FindOption{Filter: []Filter{f}})
+			var filterErr *FilterError
+			assert.ErrorAs(t, result.Err, &filterErr, "%+v", f)
+		}
+	})
+}
+
+// newSQLiteStore is a store of T in a new SQLite database.
+func newSQLiteStore[T any](t *testing.T) *gormDb[T] {
+	client, err := NewSQLClient(SQLConfig{
+		Driver: "sqlite",
+		DSN:    filepath.Join(t.TempDir(), "filter.db"),
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	require.NoError(t, client.Migrate(new(T)))
+	return NewGormDB[T](client).(*gormDb[T])
+}
+
+// newMongoStore is a store of T in an empty collection of the server of
+// MONGO_URI, the test is skipped when it is not set.
+func newMongoStore[T any](t *testing.T) *mongDb[T] {
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		t.Skip("MONGO_URI is not set")
+	}
+
+	client, err := NewMongoClient(MongoConfig{URI: uri, Database: "db_test"})
+	require.NoError(t, err)
+	var model T
+	store := NewMongoDB(model, client).(*mongDb[T])
+	require.NoError(t, store.db.Drop(context.Background()))
+	t.Cleanup(func() { store.db.Drop(context.Background()) })
+	return store
+}
+
+func TestFilterGorm(t *testing.T) {
+	testFilters(t, newSQLiteStore[filterItem](t))
+}
+
+// TestFilterMongo runs against the server of MONGO_URI, skipped when not set.
+func TestFilterMongo(t *testing.T) {
+	testFilters(t, newMongoStore[filterItem](t))
+}
+
+func TestMongoAnd(t *testing.T) {
+	filter, err := mongoAnd([]Filter{
+		{Key: "id", Value: "1"},
+		{Key: "price", Operator: Gte, Value: 10},
+		{Key: "price", Operator: Lt, Value: 500},
+		{Key: "name", Operator: Like, Value: "a.b%"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{
+		"_id":   "1",
+		"price": bson.M{"$gte": 10, "$lt": 500},
+		"name":  bson.M{"$regex": `^a\.b.*$`, "$options": "i"},
+	}, filter)
+
+	filter, err = mongoAnd([]Filter{
+		{Key: "name", Value: "a"},
+		{Key: "name", Value: "b"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"name": "a", "$and": bson.A{bson.M{"name": "b"}}}, filter)
+}
+
+func TestLikePattern(t *testing.T) {
+	assert.Equal(t, `^50%_off\\.*$`, likePattern(`50\%\_off\\%`))
+	assert.Equal(t, `^a.b.*$`, likePattern(`a_b%`))
+}
+
+func TestSQLCondition(t *testing.T) {
+	b := sqlBuilder{dialect: "postgres", column: func(key string) (string, error) { return `"` + key + `"`, nil }}
+	query, args, err := b.and([]Filter{
+		{Key: "name", Operator: Like, Value: "%phone%"},
+		{Or: []Filter{
+			{Key: "price", Operator: Gt, Value: 10},
+			{Key: "id", Operator: In, Value: []string{"1", "2"}},
+		}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `"name" ILIKE ? AND ("price" > ? OR "id" IN ?)`, query)
+	assert.Equal(t, []interface{}{"%phone%", 10, []interface{}{"1", "2"}}, args)
+}
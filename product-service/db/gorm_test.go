@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGormRejectsUnknownColumns(t *testing.T) {
+	store := newSQLiteStore[filterItem](t)
+	ctx := context.Background()
+	require.NoError(t, store.Create(ctx, filterItems[0]).Err)
+
+	for name, option := range map[string]FindOption{
+		"filter":     {Filter: []Filter{{Key: "name = 'x' OR 1=1 --", Value: "x"}}},
+		"or":         {Filter: []Filter{{Or: []Filter{{Key: "1=1) --", Value: 1}}}}},
+		"sort":       {Sort: map[string]SortDirection{"(SELECT 1)": ASC}},
+		"projection": {Projection: "name,(SELECT 1)"},
+	} {
+		var filterErr *FilterError
+		assert.ErrorAs(t, store.Find(ctx, option).Err, &filterErr, name)
+		assert.ErrorAs(t, store.FindOne(ctx, option).Err, &filterErr, name)
+	}
+	var filterErr *FilterError
+	assert.ErrorAs(t, store.Count(ctx, FindOption{Filter: []Filter{{Key: "1=1 --", Value: 1}}}).Err, &filterErr)
+	assert.ErrorAs(t, store.Update(ctx, map[string]interface{}{"1=1 --": 1}, filterItem{Name: "x"}).Err, &filterErr)
+	assert.ErrorAs(t, store.Delete(ctx, map[string]interface{}{"1=1 --": 1}).Err, &filterErr)
+}
+
+func TestGormRaw(t *testing.T) {
+	store := newSQLiteStore[filterItem](t)
+	ctx := context.Background()
+	item := filterItem{ID: "1", Name: "O'Brien", Price: 10}
+	require.NoError(t, store.Create(ctx, item).Err)
+
+	result := store.Find(ctx, FindOption{
+		Filter:     []Filter{{Key: "name", Value: "O'Brien"}, {Key: "Price", Operator: Gte, Value: 10}},
+		Sort:       map[string]SortDirection{"price": DESC},
+		Projection: "id,name",
+	})
+	require.NoError(t, result.Err)
+	assert.Equal(t, []filterItem{{ID: "1", Name: "O'Brien"}}, result.Data)
+	assert.Equal(t, "SELECT `id`,`name` FROM `filter_items` WHERE `name` = \"O'Brien\" AND `price` >= 10 ORDER BY `price` DESC LIMIT 10", result.Raw)
+
+	one := store.FindOne(ctx, FindOption{Filter: []Filter{{Key: "id", Value: "1"}}})
+	require.NoError(t, one.Err)
+	assert.Equal(t, item, one.Data)
+	assert.Contains(t, one.Raw, "WHERE `id` = \"1\" ORDER BY `filter_items`.`id` LIMIT 1")
+}
+
+func TestGormUpdateAndDelete(t *testing.T) {
+	store := newSQLiteStore[filterItem](t)
+	ctx := context.Background()
+	for _, item := range filterItems {
+		require.NoError(t, store.Create(ctx, item).Err)
+	}
+
+	// the id of update is not written
+	result := store.Update(ctx, map[string]interface{}{"id": "2"}, filterItem{ID: "9", Price: 450})
+	require.NoError(t, result.Err)
+	assert.Equal(t, int64(1), result.Count)
+	one := store.FindOne(ctx, FindOption{Filter: []Filter{{Key: "id", Value: "2"}}})
+	require.NoError(t, one.Err)
+	assert.Equal(t, filterItem{ID: "2", Name: "apple watch", Price: 450}, one.Data)
+
+	result = store.Update(ctx, Filter{Key: "price", Operator: Lt, Value: 100}, filterItem{Quantity: 1})
+	require.NoError(t, result.Err)
+	assert.Equal(t, int64(2), result.Count)
+
+	deleted := store.Delete(ctx, []Filter{{Key: "quantity", Value: 1}})
+	require.NoError(t, deleted.Err)
+	assert.Equal(t, int64(2), deleted.Count)
+	assert.Equal(t, int64(2), store.Count(ctx).Count)
+}
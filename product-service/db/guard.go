@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"gorm.io/gorm"
+)
+
+// Guard runs a call unless the database is deemed unavailable, returning its
+// own error then, e.g. an ms.Guard with a circuit breaker and a bulkhead.
+type Guard interface {
+	Do(ctx context.Context, fn func() error) error
+}
+
+type guardedDb[T any] struct {
+	store DataStore[T]
+	guard Guard
+}
+
+// Guarded runs the queries of store through guard, so that a degraded
+// database fails fast with the error of guard instead of holding every
+// request for its full timeout. A missing document or an invalid filter is
+// not a failure.
+//
+//	guard := ms.NewGuard("mongo.product", ms.GuardConfig{Breaker: &ms.BreakerConfig{}})
+//	store := db.Guarded(db.NewMongoDB(model.Product{}, client), guard)
+func Guarded[T any](store DataStore[T], guard Guard) DataStore[T] {
+	return &guardedDb[T]{store: store, guard: guard}
+}
+
+// failure is the error of a query as seen by the breaker.
+func failure(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	// mistakes of the caller, the database is fine
+	var filterErr *FilterError
+	if errors.As(err, &filterErr) || errors.Is(err, ErrEmptyFilter) || errors.Is(err, ErrNoSoftDelete) {
+		return nil
+	}
+	return err
+}
+
+// run calls query through the guard, err is the error of the guard when it
+// rejected the call.
+func run[R any](ctx context.Context, guard Guard, query func() Result[R]) Result[R] {
+	var result Result[R]
+	err := guard.Do(ctx, func() error {
+		result = query()
+		return failure(result.Err)
+	})
+	if err != nil && result.Err == nil {
+		result.Err = err
+	}
+	return result
+}
+
+func (g *guardedDb[T]) Find(ctx context.Context, findOption ...FindOption) Result[[]T] {
+	return run(ctx, g.guard, func() Result[[]T] { return g.store.Find(ctx, findOption...) })
+}
+
+func (g *guardedDb[T]) Count(ctx context.Context, findOption ...FindOption) Result[int64] {
+	return run(ctx, g.guard, func() Result[int64] { return g.store.Count(ctx, findOption...) })
+}
+
+func (g *guardedDb[T]) Create(ctx context.Context, model T) Result[T] {
+	return run(ctx, g.guard, func() Result[T] { return g.store.Create(ctx, model) })
+}
+
+func (g *guardedDb[T]) FindOne(ctx context.Context, findOption ...FindOption) Result[T] {
+	return run(ctx, g.guard, func() Result[T] { return g.store.FindOne(ctx, findOption...) })
+}
+
+func (g *guardedDb[T]) Update(ctx context.Context, filter interface{}, update T) Result[int64] {
+	return run(ctx, g.guard, func() Result[int64] { return g.store.Update(ctx, filter, update) })
+}
+
+func (g *guardedDb[T]) FindAndCount(ctx context.Context, findOption ...FindOption) Result[[]T] {
+	return run(ctx, g.guard, func() Result[[]T] { return g.store.FindAndCount(ctx, findOption...) })
+}
+
+func (g *guardedDb[T]) Delete(ctx context.Context, filter interface{}) Result[int64] {
+	return run(ctx, g.guard, func() Result[int64] { return g.store.Delete(ctx, filter) })
+}
+
+func (g *guardedDb[T]) Restore(ctx context.Context, filter interface{}) Result[int64] {
+	return run(ctx, g.guard, func() Result[int64] { return g.store.Restore(ctx, filter) })
+}
+
+func (g *guardedDb[T]) HardDelete(ctx context.Context, filter interface{}) Result[int64] {
+	return run(ctx, g.guard, func() Result[int64] { return g.store.HardDelete(ctx, filter) })
+}
+
+func (g *guardedDb[T]) CreateMany(ctx context.Context, models []T, opts ...BulkOptions) Result[BulkResult] {
+	return run(ctx, g.guard, func() Result[BulkResult] { return g.store.CreateMany(ctx, models, opts...) })
+}
+
+func (g *guardedDb[T]) Upsert(ctx context.Context, model T, keys ...string) Result[T] {
+	return run(ctx, g.guard, func() Result[T] { return g.store.Upsert(ctx, model, keys...) })
+}
+
+func (g *guardedDb[T]) UpsertMany(ctx context.Context, models []T, opts ...BulkOptions) Result[BulkResult] {
+	return run(ctx, g.guard, func() Result[BulkResult] { return g.store.UpsertMany(ctx, models, opts...) })
+}
+
+func (g *guardedDb[T]) BulkWrite(ctx context.Context, ops []WriteOp[T], opts ...BulkOptions) Result[BulkResult] {
+	return run(ctx, g.guard, func() Result[BulkResult] { return g.store.BulkWrite(ctx, ops, opts...) })
+}
@@ -3,8 +3,11 @@ package db
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -19,6 +22,8 @@ type mongDb[T any] struct {
 	config MongoConfig
 }
 
+const defaultQueryTimeout = 10 * time.Second
+
 type MongoConfig struct {
 	URI            string
 	Database       string
@@ -27,6 +32,8 @@ type MongoConfig struct {
 	ConnectTimeout time.Duration
 	RetryWrites    bool
 	RetryReads     bool
+	// QueryTimeout bounds queries whose context has no deadline, default 10s.
+	QueryTimeout time.Duration
 }
 
 type MongoClient struct {
@@ -90,49 +97,56 @@ func NewMongoDB[T any](model T, client *MongoClient) DataStore[T] {
 	}
 }
 
-func (tx *mongDb[T]) Find(findOption ...FindOption) Result[[]T] {
+// withTimeout applies the QueryTimeout unless the caller already set a deadline.
+func (tx *mongDb[T]) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+
+	timeout := tx.config.QueryTimeout
+	if timeout <= 0 {
+		timeout = defaultQueryTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+func (tx *mongDb[T]) Find(ctx context.Context, findOption ...FindOption) Result[[]T] {
 	collectionName := tx.db.Name()
 	method := "find"
 
 	model := getModel(collectionName, method)
 
-	filter := bson.M{}
-	projection := bson.M{}
-	if len(findOption) > 0 {
-		for _, option := range findOption {
-			for _, f := range option.Filter {
-				filter[f.Key] = f.Value
-			}
-
-			if option.Projection != "" {
-				for _, field := range strings.Split(option.Projection, ",") {
-					projection[field] = 1
-				}
-			}
-		}
+	page, limit, skip := paginate(findOption)
+	filter, err := tx.filter(findOption)
+	if err != nil {
+		return Result[[]T]{Err: err, Page: page, Limit: limit}
 	}
+	projection := mongoProjection(findOption)
 
-	rawDataFilter, _ := json.Marshal(filter)
-	rawData := fmt.Sprintf("%s(%s", model, strings.ReplaceAll(string(rawDataFilter), "\"", "'"))
+	rawData := fmt.Sprintf("%s(%s", model, shell(filter))
 
 	if len(projection) > 0 {
-		rawDataProjection, _ := json.Marshal(projection)
-		rawData += fmt.Sprintf(", %s", strings.ReplaceAll(string(rawDataProjection), "\"", "'"))
+		rawData += fmt.Sprintf(", %s", shell(projection))
 	}
 
 	rawData += ")"
 
+	sort := mongoSort(findOption)
+	rawData += fmt.Sprintf(".sort(%s).skip(%d).limit(%d)", shell(sort), skip, limit)
+
 	// var results Result[T]
 	results := Result[[]T]{
-		Err: nil,
-		Raw: rawData,
+		Err:   nil,
+		Raw:   rawData,
+		Page:  page,
+		Limit: limit,
 	}
 	// var queries []Filter
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := tx.withTimeout(ctx)
 	defer cancel()
 
-	opts := &options.FindOptionsBuilder{}
+	opts := options.Find().SetSort(sort).SetSkip(int64(skip)).SetLimit(int64(limit))
 
 	if len(projection) > 0 {
 		opts.SetProjection(projection)
@@ -159,19 +173,34 @@ func (tx *mongDb[T]) Find(findOption ...FindOption) Result[[]T] {
 	return results
 }
 
-func (tx *mongDb[T]) Count(findOption ...FindOption) Result[int64] {
-	return Result[int64]{
-		Err:   nil,
-		Count: 0,
+func (tx *mongDb[T]) Count(ctx context.Context, findOption ...FindOption) Result[int64] {
+	filter, err := tx.filter(findOption)
+	if err != nil {
+		return Result[int64]{Err: err}
+	}
+	result := Result[int64]{
+		Raw: fmt.Sprintf("%s(%s)", getModel(tx.db.Name(), "countDocuments"), shell(filter)),
 	}
+
+	ctx, cancel := tx.withTimeout(ctx)
+	defer cancel()
+
+	count, err := tx.db.CountDocuments(ctx, filter)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Count = count
+	return result
 }
 
 func getModel(name, method string) string {
 	return fmt.Sprintf("db.%s.%s", name, method)
 }
 
-func (tx *mongDb[T]) Create(model T) Result[T] {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (tx *mongDb[T]) Create(ctx context.Context, model T) Result[T] {
+	ctx, cancel := tx.withTimeout(ctx)
 	defer cancel()
 
 	result := Result[T]{
@@ -181,21 +210,7 @@ func (tx *mongDb[T]) Create(model T) Result[T] {
 	cmd := "InsertOne"
 	collectionName := tx.db.Name()
 
-	v := reflect.ValueOf(&model).Elem()
-
-	idField := v.FieldByName("ID")
-	if idField.Kind() == reflect.String && idField.String() == "" {
-
-		idField.SetString(uuid.New().String())
-	}
-
-	if !idField.IsValid() {
-		idField.SetString(uuid.New().String())
-	}
-
-	if idField.IsZero() {
-		idField.SetString(uuid.New().String())
-	}
+	setID(&model)
 
 	m := getModel(collectionName, cmd)
 	rawDataNew, _ := json.Marshal(model)
@@ -225,43 +240,779 @@ func (tx *mongDb[T]) Create(model T) Result[T] {
 	return result
 }
 
-func (tx *mongDb[T]) FindOne(findOption ...FindOption) Result[T] {
+func (tx *mongDb[T]) FindOne(ctx context.Context, findOption ...FindOption) Result[T] {
 	result := Result[T]{
 		Err: nil,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	filter, err := tx.filter(findOption)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	projection := mongoProjection(findOption)
+	sort := mongoSort(findOption)
+
+	result.Raw = fmt.Sprintf("%s(%s", getModel(tx.db.Name(), "find"), shell(filter))
+	if len(projection) > 0 {
+		result.Raw += fmt.Sprintf(", %s", shell(projection))
+	}
+	result.Raw += fmt.Sprintf(").sort(%s).limit(1)", shell(sort))
+
+	ctx, cancel := tx.withTimeout(ctx)
+	defer cancel()
+
+	opts := options.FindOne().SetSort(sort)
+	if len(projection) > 0 {
+		opts.SetProjection(projection)
+	}
+
+	var model T
+	if err := tx.db.FindOne(ctx, filter, opts).Decode(&model); err != nil {
+		result.Err = err
+	}
+
+	result.Data = model
+	return result
+}
+
+// Update sets the non zero fields of update, but the id, on the documents
+// matching filter that are not soft deleted, like the Updates of gorm. filter
+// is a map, a FindOption, a []Filter or a Filter, an empty one is refused. An
+// equality on the id updates one document, any other filter every matching
+// one. Count is the number of matched documents, 0 when update has no non
+// zero field and nothing is sent.
+func (tx *mongDb[T]) Update(ctx context.Context, filter interface{}, update T) Result[int64] {
+	result := Result[int64]{}
+
+	query, set, err := tx.update(filter, update)
+	if err != nil || set == nil {
+		result.Err = err
+		return result
+	}
+
+	method := "updateMany"
+	if oneID(query) {
+		method = "updateOne"
+	}
+	result.Raw = fmt.Sprintf("%s(%s, %s)", getModel(tx.db.Name(), method), shell(query), shell(set))
+
+	ctx, cancel := tx.withTimeout(ctx)
+	defer cancel()
+
+	var res *mongo.UpdateResult
+	if method == "updateOne" {
+		res, err = tx.db.UpdateOne(ctx, query, set)
+	} else {
+		res, err = tx.db.UpdateMany(ctx, query, set)
+	}
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Count = res.MatchedCount
+	return result
+}
+
+// Delete soft deletes the documents matching filter when T has a DeletedAt
+// field, deletes them otherwise. filter is that of Update, an empty one is
+// refused.
+func (tx *mongDb[T]) Delete(ctx context.Context, filter interface{}) Result[int64] {
+	field, ok := deletedAt[T]()
+	if !ok {
+		return tx.HardDelete(ctx, filter)
+	}
+	key := bsonName(field)
+	return tx.setDeletedAt(ctx, filter, Filter{Key: key, Value: nil}, bson.M{"$set": bson.M{key: time.Now()}})
+}
+
+func (tx *mongDb[T]) Restore(ctx context.Context, filter interface{}) Result[int64] {
+	field, ok := deletedAt[T]()
+	if !ok {
+		return Result[int64]{Err: ErrNoSoftDelete}
+	}
+	key := bsonName(field)
+	return tx.setDeletedAt(ctx, filter, Filter{Key: key, Operator: Ne, Value: nil}, bson.M{"$unset": bson.M{key: ""}})
+}
+
+// setID sets a new id on model when its string ID is empty.
+func setID[T any](model *T) {
+	id := reflect.ValueOf(model).Elem().FieldByName("ID")
+	if id.Kind() == reflect.String && id.String() == "" {
+		id.SetString(uuid.New().String())
+	}
+}
+
+// document is model as a bson document.
+func document(model interface{}) (bson.M, error) {
+	data, err := bson.Marshal(model)
+	if err != nil {
+		return nil, err
+	}
+	doc := bson.M{}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// fields are the fields of model a write sets.
+func (tx *mongDb[T]) fields(model T) (bson.M, error) {
+	fields, err := document(model)
+	if err != nil {
+		return nil, err
+	}
+	// the id is immutable, it may only select the document
+	delete(fields, "_id")
+	// a document is restored by Restore only
+	if field, ok := deletedAt[T](); ok {
+		delete(fields, bsonName(field))
+	}
+	return fields, nil
+}
+
+// set is the $set of the non zero fields of update, like the Updates of
+// gorm, empty when there are none.
+func (tx *mongDb[T]) set(update T) (bson.M, error) {
+	fields, err := tx.fields(update)
+	if err != nil {
+		return nil, err
+	}
+	zero, err := document(*new(T))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range fields {
+		if z, ok := zero[key]; ok && reflect.DeepEqual(value, z) {
+			delete(fields, key)
+		}
+	}
+	return fields, nil
+}
+
+// update is the query and the $set of Update, the $set is nil when update
+// has no non zero field.
+func (tx *mongDb[T]) update(filter interface{}, update T) (bson.M, bson.M, error) {
+	filters, err := writeFilters(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	query, err := mongoAnd(tx.scope(filters, false))
+	if err != nil {
+		return nil, nil, err
+	}
+	fields, err := tx.set(update)
+	if err != nil || len(fields) == 0 {
+		return query, nil, err
+	}
+	return query, bson.M{"$set": fields}, nil
+}
+
+// oneID tells whether query selects a document by the equality of its id,
+// it matches one document at most.
+func oneID(query bson.M) bool {
+	id, ok := query["_id"]
+	if !ok {
+		return false
+	}
+	_, operators := id.(bson.M)
+	return !operators
+}
+
+// setDeletedAt applies update, which sets or unsets DeletedAt, to the
+// documents matching filter and state.
+func (tx *mongDb[T]) setDeletedAt(ctx context.Context, filter interface{}, state Filter, update bson.M) Result[int64] {
+	result := Result[int64]{}
+
+	filters, err := writeFilters(filter)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	query, err := mongoAnd(append(filters[:len(filters):len(filters)], state))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Raw = fmt.Sprintf("%s(%s, %s)", getModel(tx.db.Name(), "updateMany"), shell(query), shell(update))
+
+	ctx, cancel := tx.withTimeout(ctx)
+	defer cancel()
+
+	res, err := tx.db.UpdateMany(ctx, query, update)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Count = res.MatchedCount
+	return result
+}
+
+func (tx *mongDb[T]) HardDelete(ctx context.Context, filter interface{}) Result[int64] {
+	result := Result[int64]{}
+
+	filters, err := writeFilters(filter)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	query, err := mongoAnd(filters)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Raw = fmt.Sprintf("%s(%s)", getModel(tx.db.Name(), "deleteMany"), shell(query))
+
+	ctx, cancel := tx.withTimeout(ctx)
+	defer cancel()
+
+	res, err := tx.db.DeleteMany(ctx, query)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Count = res.DeletedCount
+	return result
+}
+
+// CreateMany inserts models with InsertMany, BatchSize documents at a time,
+// the new ids are set on models.
+func (tx *mongDb[T]) CreateMany(ctx context.Context, models []T, opts ...BulkOptions) Result[BulkResult] {
+	o := bulkOptions(opts)
+	result := BulkResult{Items: make([]ItemResult, len(models))}
+	if len(models) == 0 {
+		return result.result("")
+	}
+
+	for i := range models {
+		setID(&models[i])
+	}
+	raw := fmt.Sprintf("%s(%s, %s)", getModel(tx.db.Name(), "insertMany"), shell(models[:min(len(models), o.BatchSize)]), shell(bson.M{"ordered": o.Ordered}))
+
+	batches(len(models), o.BatchSize, func(start, end int) bool {
+		ctx, cancel := tx.withTimeout(ctx)
+		defer cancel()
+
+		_, err := tx.db.InsertMany(ctx, models[start:end], options.InsertMany().SetOrdered(o.Ordered))
+		if err != nil {
+			return mongoBulkFailure(&result, span(start, end), err, o.Ordered)
+		}
+		return false
+	})
+	result.Inserted = int64(len(models) - len(result.Failed()))
+	return result.result(raw)
+}
+
+// Upsert creates model or updates the document matching its keys with
+// FindOneAndUpdate, Data is the document written.
+func (tx *mongDb[T]) Upsert(ctx context.Context, model T, keys ...string) Result[T] {
+	result := Result[T]{}
+
+	filter, update, err := tx.upsert(&model, bulkOptions([]BulkOptions{{Keys: keys}}).Keys)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Raw = fmt.Sprintf("%s(%s, %s, %s)", getModel(tx.db.Name(), "findOneAndUpdate"), shell(filter), shell(update), shell(bson.M{"upsert": true, "returnDocument": "after"}))
+
+	ctx, cancel := tx.withTimeout(ctx)
 	defer cancel()
 
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	if err := tx.db.FindOneAndUpdate(ctx, filter, update, opts).Decode(&result.Data); err != nil {
+		result.Err = err
+		return result
+	}
+	return result
+}
+
+// upsert is the filter on keys and the update of an upsert of model, whose
+// id is set when empty. The id of an existing document is kept.
+func (tx *mongDb[T]) upsert(model *T, keys []string) (bson.M, bson.M, error) {
+	setID(model)
+	doc, err := document(*model)
+	if err != nil {
+		return nil, nil, err
+	}
 	filter := bson.M{}
-	if len(findOption) > 0 {
-		for _, option := range findOption {
-			for _, f := range option.Filter {
-				filter[f.Key] = f.Value
+	for _, key := range keys {
+		filter[mongoField(key)] = doc[mongoField(key)]
+	}
+	fields, err := tx.fields(*model)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	update := bson.M{"$set": fields}
+	if _, ok := filter["_id"]; !ok {
+		update["$setOnInsert"] = bson.M{"_id": doc["_id"]}
+	}
+	return filter, update, nil
+}
+
+// UpsertMany upserts models with BulkWrite, BatchSize documents at a time,
+// the new ids are set on models.
+func (tx *mongDb[T]) UpsertMany(ctx context.Context, models []T, opts ...BulkOptions) Result[BulkResult] {
+	o := bulkOptions(opts)
+	result := BulkResult{Items: make([]ItemResult, len(models))}
+
+	var writes []mongo.WriteModel
+	var index []int
+	for i := range models {
+		filter, update, err := tx.upsert(&models[i], o.Keys)
+		if err != nil {
+			result.Items[i].Err = err
+			if o.Ordered {
+				result.skip(i + 1)
+				break
 			}
+			continue
 		}
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+		index = append(index, i)
 	}
+	return tx.bulkWrite(ctx, &result, writes, index, o)
+}
 
-	var model T
-	id := fmt.Sprintf("%s", filter["id"])
+// BulkWrite runs ops with BulkWrite, BatchSize ops at a time. The new ids of
+// the inserted and upserted models are set on ops.
+func (tx *mongDb[T]) BulkWrite(ctx context.Context, ops []WriteOp[T], opts ...BulkOptions) Result[BulkResult] {
+	o := bulkOptions(opts)
+	result := BulkResult{Items: make([]ItemResult, len(ops))}
+
+	var writes []mongo.WriteModel
+	var index []int
+	for i := range ops {
+		write, err := tx.writeModel(&ops[i], o.Keys)
+		if err != nil {
+			result.Items[i].Err = err
+			if o.Ordered {
+				result.skip(i + 1)
+				break
+			}
+			continue
+		}
+		if write == nil {
+			// an update without non zero field, nothing to write
+			continue
+		}
+		writes = append(writes, write)
+		index = append(index, i)
+	}
+	return tx.bulkWrite(ctx, &result, writes, index, o)
+}
+
+// writeModel is the write of op, like that of Create, Update, Upsert or
+// Delete, nil for an update without non zero field.
+func (tx *mongDb[T]) writeModel(op *WriteOp[T], keys []string) (mongo.WriteModel, error) {
+	switch op.Kind {
+	case InsertWrite:
+		setID(&op.Model)
+		return mongo.NewInsertOneModel().SetDocument(op.Model), nil
+	case UpsertWrite:
+		filter, update, err := tx.upsert(&op.Model, keys)
+		if err != nil {
+			return nil, err
+		}
+		return mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true), nil
+	case UpdateWrite:
+		query, set, err := tx.update(op.Filter, op.Model)
+		if err != nil || set == nil {
+			return nil, err
+		}
+		return mongo.NewUpdateManyModel().SetFilter(query).SetUpdate(set), nil
+	case DeleteWrite:
+		filters, err := writeFilters(op.Filter)
+		if err != nil {
+			return nil, err
+		}
+		field, soft := deletedAt[T]()
+		if soft {
+			filters = append(filters[:len(filters):len(filters)], Filter{Key: bsonName(field), Value: nil})
+		}
+		query, err := mongoAnd(filters)
+		if err != nil {
+			return nil, err
+		}
+		if soft {
+			update := bson.M{"$set": bson.M{bsonName(field): time.Now()}}
+			return mongo.NewUpdateManyModel().SetFilter(query).SetUpdate(update), nil
+		}
+		return mongo.NewDeleteManyModel().SetFilter(query), nil
+	}
+	return nil, fmt.Errorf("unknown write kind %d", op.Kind)
+}
+
+// bulkWrite sends writes, the writes of the items at index, in batches and
+// records their outcome on result.
+func (tx *mongDb[T]) bulkWrite(ctx context.Context, result *BulkResult, writes []mongo.WriteModel, index []int, o BulkOptions) Result[BulkResult] {
+	raw := fmt.Sprintf("%s([%d operations], %s)", getModel(tx.db.Name(), "bulkWrite"), len(writes), shell(bson.M{"ordered": o.Ordered}))
+
+	batches(len(writes), o.BatchSize, func(start, end int) bool {
+		ctx, cancel := tx.withTimeout(ctx)
+		defer cancel()
+
+		res, err := tx.db.BulkWrite(ctx, writes[start:end], options.BulkWrite().SetOrdered(o.Ordered))
+		if res != nil {
+			result.Inserted += res.InsertedCount
+			result.Matched += res.MatchedCount
+			result.Modified += res.ModifiedCount
+			result.Upserted += res.UpsertedCount
+			result.Deleted += res.DeletedCount
+		}
+		if err != nil {
+			return mongoBulkFailure(result, index[start:end], err, o.Ordered)
+		}
+		return false
+	})
+	return result.result(raw)
+}
+
+// mongoBulkFailure records on result the errors of a batch of the items at
+// index that failed with err, and tells whether the bulk operation stops.
+// The server reports the writes that failed, any other error fails the
+// whole batch.
+func mongoBulkFailure(result *BulkResult, index []int, err error, ordered bool) bool {
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0 {
+		for _, writeErr := range bulkErr.WriteErrors {
+			result.Items[index[writeErr.Index]].Err = writeErr.WriteError
+		}
+		if ordered {
+			result.skip(index[bulkErr.WriteErrors[0].Index] + 1)
+		}
+		return ordered
+	}
 
-	err := tx.db.FindOne(ctx, bson.M{
-		"_id": id,
-	}).Decode(&model)
+	for _, i := range index {
+		if result.Items[i].Err == nil {
+			result.Items[i].Err = err
+		}
+	}
+	if ordered {
+		result.skip(index[len(index)-1] + 1)
+	}
+	return ordered
+}
+
+// FindAndCount finds the documents like Find and counts every match in one
+// aggregation, Count is the total.
+func (tx *mongDb[T]) FindAndCount(ctx context.Context, findOption ...FindOption) Result[[]T] {
+	page, limit, _ := paginate(findOption)
+	pipeline, err := tx.findAndCount(findOption)
+	if err != nil {
+		return Result[[]T]{Err: err, Page: page, Limit: limit}
+	}
+
+	result := Result[[]T]{
+		Raw:   fmt.Sprintf("%s(%s)", getModel(tx.db.Name(), "aggregate"), shell(pipeline)),
+		Page:  page,
+		Limit: limit,
+	}
+
+	ctx, cancel := tx.withTimeout(ctx)
+	defer cancel()
+
+	cursor, err := tx.db.Aggregate(ctx, pipeline)
 	if err != nil {
 		result.Err = err
+		return result
 	}
+	defer cursor.Close(ctx)
 
-	result.Data = model
+	var facets []struct {
+		Data  []T `bson:"data"`
+		Count []struct {
+			Count int64 `bson:"count"`
+		} `bson:"count"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
+		result.Err = err
+		return result
+	}
+
+	if len(facets) > 0 {
+		result.Data = facets[0].Data
+		if len(facets[0].Count) > 0 {
+			result.Count = facets[0].Count[0].Count
+		}
+	}
 	return result
 }
 
-func (tx *mongDb[T]) Update(filter interface{}, update T) error {
-	return nil
+// findAndCount is the aggregation of FindAndCount, the page of the matching
+// documents in data and their total in count.
+func (tx *mongDb[T]) findAndCount(findOption []FindOption) (bson.A, error) {
+	_, limit, skip := paginate(findOption)
+	filter, err := tx.filter(findOption)
+	if err != nil {
+		return nil, err
+	}
+	projection := mongoProjection(findOption)
+
+	data := bson.A{
+		bson.M{"$sort": mongoSort(findOption)},
+		bson.M{"$skip": skip},
+		bson.M{"$limit": limit},
+	}
+	if len(projection) > 0 {
+		data = append(data, bson.M{"$project": projection})
+	}
+	return bson.A{
+		bson.M{"$match": filter},
+		bson.M{"$facet": bson.M{
+			"data":  data,
+			"count": bson.A{bson.M{"$count": "count"}},
+		}},
+	}, nil
 }
 
-func (tx *mongDb[T]) FindAndCount(findOption ...FindOption) Result[[]T] {
-	return Result[[]T]{
-		Err: nil,
+// filter is the query of findOption, skipping the soft deleted documents
+// unless WithDeleted is set.
+func (tx *mongDb[T]) filter(findOption []FindOption) (bson.M, error) {
+	return mongoAnd(tx.scope(filters(findOption), withDeleted(findOption)))
+}
+
+// scope adds to filters the condition skipping the soft deleted documents,
+// unless deleted is set.
+func (tx *mongDb[T]) scope(filters []Filter, deleted bool) []Filter {
+	if field, ok := deletedAt[T](); ok && !deleted {
+		return notDeleted(filters, bsonName(field))
+	}
+	return filters
+}
+
+// mongoAnd merges the conditions of filters in one document, the operators
+// on a same field in one operator document. Conditions that do not merge,
+// like two equalities on a field, go to $and.
+func mongoAnd(filters []Filter) (bson.M, error) {
+	query := bson.M{}
+	var and bson.A
+	for _, f := range filters {
+		cond, err := mongoCondition(f)
+		if err != nil {
+			return nil, err
+		}
+
+		keys := make([]string, 0, len(cond))
+		for key := range cond {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := cond[key]
+			if key == "$and" {
+				and = append(and, value.(bson.A)...)
+				continue
+			}
+			existing, ok := query[key]
+			if !ok {
+				query[key] = value
+				continue
+			}
+			if merged, ok := mergeOperators(existing, value); ok {
+				query[key] = merged
+				continue
+			}
+			and = append(and, bson.M{key: value})
+		}
+	}
+	if len(and) > 0 {
+		query["$and"] = and
+	}
+	return query, nil
+}
+
+// mongoCondition is the query document of f.
+func mongoCondition(f Filter) (bson.M, error) {
+	if len(f.Or) > 0 {
+		or := make(bson.A, len(f.Or))
+		for i, g := range f.Or {
+			cond, err := mongoAnd([]Filter{g})
+			if err != nil {
+				return nil, err
+			}
+			or[i] = cond
+		}
+		return bson.M{"$or": or}, nil
+	}
+	if len(f.And) > 0 {
+		return mongoAnd(f.And)
+	}
+
+	op, err := f.operator()
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	switch op {
+	case Eq:
+		value = f.Value
+	case In, Nin:
+		value = bson.M{"$" + string(op): bson.A(f.values())}
+	case Like:
+		value = bson.M{"$regex": likePattern(f.Value.(string)), "$options": "i"}
+	case Regex:
+		value = bson.M{"$regex": f.Value}
+	case Exists:
+		// a null field does not exist in SQL, $exists would match it
+		if f.Value.(bool) {
+			value = bson.M{"$ne": nil}
+		} else {
+			value = nil
+		}
+	default:
+		value = bson.M{"$" + string(op): f.Value}
+	}
+	return bson.M{mongoField(f.Key): value}, nil
+}
+
+// mergeOperators merges the operator documents a and b when they do not
+// share an operator.
+func mergeOperators(a, b interface{}) (bson.M, bool) {
+	x, ok := a.(bson.M)
+	if !ok || !operators(x) {
+		return nil, false
+	}
+	y, ok := b.(bson.M)
+	if !ok || !operators(y) {
+		return nil, false
+	}
+
+	merged := bson.M{}
+	for key, value := range x {
+		merged[key] = value
+	}
+	for key, value := range y {
+		if _, ok := merged[key]; ok {
+			return nil, false
+		}
+		merged[key] = value
+	}
+	return merged, true
+}
+
+func operators(doc bson.M) bool {
+	for key := range doc {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+	return len(doc) > 0
+}
+
+// likePattern is the anchored regular expression of the SQL pattern like.
+func likePattern(like string) string {
+	var b strings.Builder
+	b.WriteByte('^')
+	escaped := false
+	for _, r := range like {
+		switch {
+		case escaped:
+			escaped = false
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			b.WriteString(".*")
+		case r == '_':
+			b.WriteByte('.')
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteByte('$')
+	return b.String()
+}
+
+func mongoField(key string) string {
+	if key == "id" {
+		return "_id"
+	}
+	return key
+}
+
+// mongoSort is the sort of findOption, by _id when it has none so that the
+// pages are stable.
+func mongoSort(findOption []FindOption) bson.D {
+	keys, directions := sortKeys(findOption)
+	sort := bson.D{}
+	for _, key := range keys {
+		order := 1
+		if directions[key] == DESC {
+			order = -1
+		}
+		sort = append(sort, bson.E{Key: mongoField(key), Value: order})
+	}
+	if len(sort) == 0 {
+		sort = append(sort, bson.E{Key: "_id", Value: 1})
+	}
+	return sort
+}
+
+func mongoProjection(findOption []FindOption) bson.M {
+	projection := bson.M{}
+	for _, option := range findOption {
+		if option.Projection != "" {
+			for _, field := range strings.Split(option.Projection, ",") {
+				projection[field] = 1
+			}
+		}
+	}
+	return projection
+}
+
+// shell renders v like the mongo shell does, for Result.Raw.
+func shell(v interface{}) string {
+	data, _ := json.Marshal(shellValue(v))
+	return strings.ReplaceAll(string(data), "\"", "'")
+}
+
+// shellValue keeps the key order of the bson.D in v once json encoded.
+func shellValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case bson.D:
+		doc := make(shellDoc, len(v))
+		for i, e := range v {
+			doc[i] = bson.E{Key: e.Key, Value: shellValue(e.Value)}
+		}
+		return doc
+	case bson.M:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[key] = shellValue(value)
+		}
+		return m
+	case bson.A:
+		a := make([]interface{}, len(v))
+		for i, value := range v {
+			a[i] = shellValue(value)
+		}
+		return a
+	}
+	return v
+}
+
+type shellDoc []bson.E
+
+func (d shellDoc) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, e := range d {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(e.Key)
+		value, err := json.Marshal(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
 	}
+	b.WriteByte('}')
+	return []byte(b.String()), nil
 }
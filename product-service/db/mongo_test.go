@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestMongoUpdateDocument(t *testing.T) {
+	tx := &mongDb[softItem]{}
+	query, set, err := tx.update(map[string]interface{}{"id": "1"}, softItem{ID: "2", Name: "new"})
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"_id": "1", "deleted_at": nil}, query)
+	// the id and DeletedAt are never set
+	assert.Equal(t, bson.M{"$set": bson.M{"name": "new"}}, set)
+
+	// zero fields are left as is, like gorm does
+	items := &mongDb[filterItem]{}
+	_, set, err = items.update(Filter{Key: "id", Value: "1"}, filterItem{Price: 5})
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"$set": bson.M{"price": 5.0}}, set)
+	_, set, err = items.update(Filter{Key: "id", Value: "1"}, filterItem{Note: note("")})
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"$set": bson.M{"note": ""}}, set)
+	_, set, err = items.update(Filter{Key: "id", Value: "1"}, filterItem{})
+	require.NoError(t, err)
+	assert.Nil(t, set)
+
+	_, _, err = items.update(nil, filterItem{Name: "x"})
+	assert.ErrorIs(t, err, ErrEmptyFilter)
+}
+
+func TestMongoUpdateOne(t *testing.T) {
+	for filter, want := range map[string]struct {
+		filter Filter
+		one    bool
+	}{
+		"equality": {Filter{Key: "id", Value: "1"}, true},
+		"in":       {Filter{Key: "id", Operator: In, Value: []string{"1", "2"}}, false},
+		"ne":       {Filter{Key: "id", Operator: Ne, Value: "1"}, false},
+		"name":     {Filter{Key: "name", Value: "a"}, false},
+	} {
+		query, err := mongoAnd([]Filter{want.filter})
+		require.NoError(t, err)
+		assert.Equal(t, want.one, oneID(query), filter)
+	}
+}
+
+func TestMongoFindAndCountPipeline(t *testing.T) {
+	tx := &mongDb[softItem]{}
+	pipeline, err := tx.findAndCount([]FindOption{{
+		Filter:     []Filter{{Key: "name", Operator: Ne, Value: "x"}},
+		Sort:       map[string]SortDirection{"name": DESC},
+		Page:       3,
+		Limit:      10,
+		Projection: "name",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, bson.A{
+		bson.M{"$match": bson.M{"name": bson.M{"$ne": "x"}, "deleted_at": nil}},
+		bson.M{"$facet": bson.M{
+			"data": bson.A{
+				bson.M{"$sort": bson.D{{Key: "name", Value: -1}}},
+				bson.M{"$skip": 20},
+				bson.M{"$limit": 10},
+				bson.M{"$project": bson.M{"name": 1}},
+			},
+			"count": bson.A{bson.M{"$count": "count"}},
+		}},
+	}, pipeline)
+
+	// every document, by _id, with the soft deleted ones
+	pipeline, err = tx.findAndCount([]FindOption{{WithDeleted: true}})
+	require.NoError(t, err)
+	assert.Equal(t, bson.A{
+		bson.M{"$match": bson.M{}},
+		bson.M{"$facet": bson.M{
+			"data": bson.A{
+				bson.M{"$sort": bson.D{{Key: "_id", Value: 1}}},
+				bson.M{"$skip": 0},
+				bson.M{"$limit": DefaultLimit},
+			},
+			"count": bson.A{bson.M{"$count": "count"}},
+		}},
+	}, pipeline)
+}
+
+// testCountAndUpdate runs the Count, FindAndCount and Update cases against
+// store, the stores must agree.
+func testCountAndUpdate(t *testing.T, store DataStore[filterItem]) {
+	ctx := context.Background()
+	for _, item := range filterItems {
+		require.NoError(t, store.Create(ctx, item).Err)
+	}
+
+	assert.Equal(t, int64(4), store.Count(ctx).Count)
+	costly := []Filter{{Key: "price", Operator: Gte, Value: 10}}
+	counted := store.Count(ctx, FindOption{Filter: costly})
+	require.NoError(t, counted.Err, counted.Raw)
+	assert.Equal(t, int64(3), counted.Count)
+
+	// 10, 399 then 999
+	page := store.FindAndCount(ctx, FindOption{Filter: costly, Sort: map[string]SortDirection{"price": ASC}, Page: 2, Limit: 2})
+	require.NoError(t, page.Err, page.Raw)
+	assert.Equal(t, int64(3), page.Count)
+	assert.Equal(t, 2, page.Page)
+	assert.Equal(t, 2, page.Limit)
+	require.Len(t, page.Data, 1)
+	assert.Equal(t, "1", page.Data[0].ID)
+
+	page = store.FindAndCount(ctx, FindOption{Filter: costly, Page: 3, Limit: 2})
+	require.NoError(t, page.Err, page.Raw)
+	assert.Equal(t, int64(3), page.Count)
+	assert.Empty(t, page.Data)
+
+	page = store.FindAndCount(ctx, FindOption{Filter: []Filter{{Key: "id", Value: "4"}}, Projection: "name"})
+	require.NoError(t, page.Err, page.Raw)
+	require.Len(t, page.Data, 1)
+	assert.Equal(t, "Cherry", page.Data[0].Name)
+	assert.Zero(t, page.Data[0].Price)
+
+	// every matched record is updated, the zero fields are left as is
+	updated := store.Update(ctx, Filter{Key: "id", Operator: In, Value: []string{"1", "2"}}, filterItem{Quantity: 7})
+	require.NoError(t, updated.Err, updated.Raw)
+	assert.Equal(t, int64(2), updated.Count)
+	updated = store.Update(ctx, map[string]interface{}{"id": "3"}, filterItem{Name: "Plantain"})
+	require.NoError(t, updated.Err, updated.Raw)
+	assert.Equal(t, int64(1), updated.Count)
+	assert.Equal(t, int64(0), store.Update(ctx, map[string]interface{}{"id": "9"}, filterItem{Name: "x"}).Count)
+
+	found := store.Find(ctx, FindOption{Sort: map[string]SortDirection{"id": ASC}})
+	require.NoError(t, found.Err)
+	require.Len(t, found.Data, 4)
+	assert.Equal(t, filterItem{ID: "1", Name: "Apple iPhone", Price: 999, Quantity: 7, Note: note("new")}, found.Data[0])
+	assert.Equal(t, filterItem{ID: "2", Name: "apple watch", Price: 399, Quantity: 7}, found.Data[1])
+	assert.Equal(t, filterItem{ID: "3", Name: "Plantain", Price: 1.5, Quantity: 100, Note: note("fruit")}, found.Data[2])
+	assert.Equal(t, filterItems[3], found.Data[3])
+}
+
+func TestCountAndUpdateGorm(t *testing.T) {
+	testCountAndUpdate(t, newSQLiteStore[filterItem](t))
+}
+
+// TestCountAndUpdateMongo runs against the server of MONGO_URI, skipped when
+// not set.
+func TestCountAndUpdateMongo(t *testing.T) {
+	testCountAndUpdate(t, newMongoStore[filterItem](t))
+}
@@ -0,0 +1,498 @@
+package db
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QuerySchema parses the query strings of the requests listing a model into
+// a FindOption. Only the fields of the schema, named by their json name, may
+// be filtered, sorted and selected, the json name must be the field name in
+// the store too.
+type QuerySchema struct {
+	fields map[string]reflect.Type
+	// operators are the filter operators of the fields limited with
+	// Operators.
+	operators map[string]map[Operator]bool
+}
+
+// NewQuerySchema is the schema of the fields of model, every stored field
+// when none is listed. It panics on a field model does not have.
+//
+//	var productQuery = db.NewQuerySchema(model.Product{}, "id", "name", "price", "quantity")
+func NewQuerySchema(model interface{}, fields ...string) *QuerySchema {
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	all := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if !field.IsExported() || name == "-" || field.Tag.Get("bson") == "-" || field.Tag.Get("gorm") == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		typ := field.Type
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		all[name] = typ
+	}
+
+	if len(fields) == 0 {
+		return &QuerySchema{fields: all}
+	}
+	schema := &QuerySchema{fields: map[string]reflect.Type{}}
+	for _, name := range fields {
+		typ, ok := all[name]
+		if !ok {
+			panic(fmt.Sprintf("db: %s has no field %s", t.Name(), name))
+		}
+		schema.fields[name] = typ
+	}
+	return schema
+}
+
+// Operators limits the filters of field to operators, the others are
+// refused. A regex can be slow to match and is run by the database, it is
+// best left off the schemas of public APIs. Operators is called on a new
+// schema, it panics on a field the schema does not have.
+//
+//	db.NewQuerySchema(model.Product{}, "id", "name").Operators("name", db.Eq, db.Ne, db.Like)
+func (s *QuerySchema) Operators(field string, operators ...Operator) *QuerySchema {
+	if _, ok := s.fields[field]; !ok {
+		panic(fmt.Sprintf("db: query schema has no field %s", field))
+	}
+	if s.operators == nil {
+		s.operators = map[string]map[Operator]bool{}
+	}
+	s.operators[field] = map[Operator]bool{}
+	for _, op := range operators {
+		s.operators[field][op] = true
+	}
+	return s
+}
+
+// allows reports whether field may be filtered with op.
+func (s *QuerySchema) allows(field string, op Operator) bool {
+	allowed, ok := s.operators[field]
+	return !ok || allowed[op]
+}
+
+// Parse reads the filter, sort, page, limit and fields parameters of a query
+// string, query returns a parameter by name, e.g. ms.IContext.Query.
+//
+//	?filter=price=gt=10;quantity=ge=1&sort=-price,name&page=2&limit=20&fields=name,price
+//
+// The filter is RSQL: comparisons are and-ed with ";" and or-ed with ",",
+// which binds weaker, and grouped in parentheses. The operators are == and
+// != (also =eq= and =ne=), =gt=, =ge=, =lt=, =le=, =in=(a,b), =out=(a,b),
+// =like=, =regex= and =exists=true|false. A value holding a reserved
+// character is quoted with ' or ", and an == value holding a * is a like
+// pattern whose only wildcard is *, e.g. name==*phone*. The operators of a
+// field may be limited with Operators. The errors are reported in a
+// QueryError.
+func (s *QuerySchema) Parse(query func(name string) string) (FindOption, error) {
+	var option FindOption
+	var errs []FieldError
+
+	if filter := query("filter"); filter != "" {
+		p := &queryParser{schema: s, input: filter}
+		f, err := p.parse()
+		if err != nil {
+			errs = append(errs, *err)
+		} else {
+			option.Filter = []Filter{f}
+		}
+	}
+
+	if sort := query("sort"); sort != "" {
+		option.Sort = map[string]SortDirection{}
+		for _, key := range strings.Split(sort, ",") {
+			direction := ASC
+			switch {
+			case strings.HasPrefix(key, "-"):
+				direction, key = DESC, key[1:]
+			case strings.HasPrefix(key, "+"):
+				key = key[1:]
+			}
+			if _, ok := s.fields[key]; !ok {
+				errs = append(errs, unknownField("sort", key))
+				continue
+			}
+			if _, ok := option.Sort[key]; !ok {
+				option.Order = append(option.Order, key)
+			}
+			option.Sort[key] = direction
+		}
+	}
+
+	if page := query("page"); page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil || n < 1 {
+			errs = append(errs, FieldError{Field: "page", Tag: "gte", Param: "1", Value: page, Message: "page must be an integer gte 1"})
+		}
+		option.Page = n
+	}
+
+	if limit := query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxLimit {
+			errs = append(errs, FieldError{Field: "limit", Tag: "max", Param: strconv.Itoa(MaxLimit), Value: limit,
+				Message: fmt.Sprintf("limit must be an integer from 1 to %d", MaxLimit)})
+		}
+		option.Limit = n
+	}
+
+	if fields := query("fields"); fields != "" {
+		var projection []string
+		for _, name := range strings.Split(fields, ",") {
+			if _, ok := s.fields[name]; !ok {
+				errs = append(errs, unknownField("fields", name))
+				continue
+			}
+			projection = append(projection, name)
+		}
+		option.Projection = strings.Join(projection, ",")
+	}
+
+	if len(errs) > 0 {
+		return FindOption{}, &QueryError{Message: "invalid query", Errors: errs}
+	}
+	return option, nil
+}
+
+// FieldError is an invalid parameter of a query.
+type FieldError struct {
+	Field   string      `json:"field"`
+	Tag     string      `json:"tag"`
+	Param   string      `json:"param,omitempty"`
+	Value   interface{} `json:"value,omitempty"`
+	Message string      `json:"message"`
+}
+
+// QueryError lists every invalid parameter of a query parsed by a
+// QuerySchema.
+type QueryError struct {
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors"`
+}
+
+func (e *QueryError) Error() string {
+	var fields []string
+	for _, f := range e.Errors {
+		fields = append(fields, f.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Message, strings.Join(fields, "; "))
+}
+
+// StatusCode is 400, the query is the one of the request.
+func (e *QueryError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+func unknownField(param, name string) FieldError {
+	return FieldError{Field: param, Tag: "field", Value: name, Message: fmt.Sprintf("%s: unknown field %q", param, name)}
+}
+
+var queryOperators = map[string]Operator{
+	"==":       Eq,
+	"!=":       Ne,
+	"=eq=":     Eq,
+	"=ne=":     Ne,
+	"=gt=":     Gt,
+	"=ge=":     Gte,
+	"=gte=":    Gte,
+	"=lt=":     Lt,
+	"=le=":     Lte,
+	"=lte=":    Lte,
+	"=in=":     In,
+	"=out=":    Nin,
+	"=nin=":    Nin,
+	"=like=":   Like,
+	"=regex=":  Regex,
+	"=exists=": Exists,
+}
+
+// queryParser parses an RSQL filter, pos is the position of the next byte
+// of input.
+type queryParser struct {
+	schema *QuerySchema
+	input  string
+	pos    int
+}
+
+func (p *queryParser) parse() (Filter, *FieldError) {
+	f, err := p.or()
+	if err != nil {
+		return Filter{}, err
+	}
+	if p.pos < len(p.input) {
+		return Filter{}, p.syntaxError("unexpected %q", p.input[p.pos])
+	}
+	return f, nil
+}
+
+func (p *queryParser) or() (Filter, *FieldError) {
+	return p.group(',', p.and, func(group []Filter) Filter { return Filter{Or: group} })
+}
+
+func (p *queryParser) and() (Filter, *FieldError) {
+	return p.group(';', p.term, func(group []Filter) Filter { return Filter{And: group} })
+}
+
+// group parses the terms separated by sep, a single term is not grouped.
+func (p *queryParser) group(sep byte, term func() (Filter, *FieldError), build func([]Filter) Filter) (Filter, *FieldError) {
+	var group []Filter
+	for {
+		f, err := term()
+		if err != nil {
+			return Filter{}, err
+		}
+		group = append(group, f)
+		if !p.consume(sep) {
+			break
+		}
+	}
+	if len(group) == 1 {
+		return group[0], nil
+	}
+	return build(group), nil
+}
+
+func (p *queryParser) term() (Filter, *FieldError) {
+	if p.consume('(') {
+		f, err := p.or()
+		if err != nil {
+			return Filter{}, err
+		}
+		if !p.consume(')') {
+			return Filter{}, p.syntaxError("missing )")
+		}
+		return f, nil
+	}
+	return p.comparison()
+}
+
+func (p *queryParser) comparison() (Filter, *FieldError) {
+	start := p.pos
+	for p.pos < len(p.input) && isSelector(p.input[p.pos]) {
+		p.pos++
+	}
+	key := p.input[start:p.pos]
+	if key == "" {
+		return Filter{}, p.syntaxError("missing field")
+	}
+
+	op, symbol, err := p.operator()
+	if err != nil {
+		return Filter{}, err
+	}
+
+	var values []string
+	if op == In || op == Nin {
+		if !p.consume('(') {
+			return Filter{}, p.syntaxError("%s needs a list of values", symbol)
+		}
+		for {
+			value, err := p.value()
+			if err != nil {
+				return Filter{}, err
+			}
+			values = append(values, value)
+			if p.consume(')') {
+				break
+			}
+			if !p.consume(',') {
+				return Filter{}, p.syntaxError("missing )")
+			}
+		}
+	} else {
+		value, err := p.value()
+		if err != nil {
+			return Filter{}, err
+		}
+		values = append(values, value)
+	}
+
+	f, err := p.filter(key, op, symbol, values)
+	if err != nil {
+		return Filter{}, err
+	}
+	// an == holding a * is a like
+	if !p.schema.allows(key, f.Operator) {
+		return Filter{}, &FieldError{Field: "filter", Tag: "operator", Param: key, Value: symbol,
+			Message: fmt.Sprintf("filter: %s cannot be filtered with %s", key, f.Operator)}
+	}
+	return f, nil
+}
+
+func (p *queryParser) operator() (Operator, string, *FieldError) {
+	rest := p.input[p.pos:]
+	var symbol string
+	switch {
+	case strings.HasPrefix(rest, "=="), strings.HasPrefix(rest, "!="):
+		symbol = rest[:2]
+	case strings.HasPrefix(rest, "="):
+		end := strings.IndexByte(rest[1:], '=')
+		if end < 0 {
+			return "", "", p.syntaxError("missing operator")
+		}
+		symbol = rest[:end+2]
+	default:
+		return "", "", p.syntaxError("missing operator")
+	}
+
+	op, ok := queryOperators[symbol]
+	if !ok {
+		return "", "", &FieldError{Field: "filter", Tag: "operator", Value: symbol, Message: fmt.Sprintf("filter: unknown operator %s", symbol)}
+	}
+	p.pos += len(symbol)
+	return op, symbol, nil
+}
+
+// value parses a quoted or unquoted value, an unquoted one ends at a
+// reserved character.
+func (p *queryParser) value() (string, *FieldError) {
+	if p.pos < len(p.input) && (p.input[p.pos] == '\'' || p.input[p.pos] == '"') {
+		quote := p.input[p.pos]
+		end := strings.IndexByte(p.input[p.pos+1:], quote)
+		if end < 0 {
+			return "", p.syntaxError("missing closing %c", quote)
+		}
+		value := p.input[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return value, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && !strings.ContainsRune(`;,()'"`, rune(p.input[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.syntaxError("missing value")
+	}
+	return p.input[start:p.pos], nil
+}
+
+// filter is the Filter of a comparison, its values converted to the type
+// of the field.
+func (p *queryParser) filter(key string, op Operator, symbol string, values []string) (Filter, *FieldError) {
+	typ, ok := p.schema.fields[key]
+	if !ok {
+		e := unknownField("filter", key)
+		return Filter{}, &e
+	}
+	invalid := func(value, reason string) *FieldError {
+		return &FieldError{Field: "filter", Tag: "value", Param: key, Value: value,
+			Message: fmt.Sprintf("filter: %s%s%s: %s", key, symbol, value, reason)}
+	}
+
+	switch op {
+	case Exists:
+		exists, err := strconv.ParseBool(values[0])
+		if err != nil {
+			return Filter{}, invalid(values[0], "not a boolean")
+		}
+		return Filter{Key: key, Operator: op, Value: exists}, nil
+	case Like, Regex:
+		if typ.Kind() != reflect.String {
+			return Filter{}, invalid(values[0], "not a text field")
+		}
+		return Filter{Key: key, Operator: op, Value: values[0]}, nil
+	case Eq:
+		if typ.Kind() == reflect.String && strings.Contains(values[0], "*") {
+			return Filter{Key: key, Operator: Like, Value: strings.ReplaceAll(likeEscaper.Replace(values[0]), "*", "%")}, nil
+		}
+	}
+
+	converted := make([]interface{}, len(values))
+	for i, value := range values {
+		v, err := convertValue(typ, value)
+		if err != nil {
+			return Filter{}, invalid(value, err.Error())
+		}
+		converted[i] = v
+	}
+	if op == In || op == Nin {
+		return Filter{Key: key, Operator: op, Value: converted}, nil
+	}
+	return Filter{Key: key, Operator: op, Value: converted[0]}, nil
+}
+
+// likeEscaper escapes the wildcards of Like in a value, only the * of an ==
+// value is one.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// convertValue converts value to a value comparable to a field of typ.
+func convertValue(typ reflect.Type, value string) (interface{}, error) {
+	if typ == timeType {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("not an RFC 3339 time")
+		}
+		return t, nil
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return value, nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("not a boolean")
+		}
+		return b, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, typ.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("not an integer")
+		}
+		return n, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, typ.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("not a positive integer")
+		}
+		return n, nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, typ.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("not a number")
+		}
+		return f, nil
+	}
+	return nil, fmt.Errorf("field cannot be compared")
+}
+
+func (p *queryParser) consume(c byte) bool {
+	if p.pos < len(p.input) && p.input[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) syntaxError(format string, args ...interface{}) *FieldError {
+	return &FieldError{
+		Field:   "filter",
+		Tag:     "syntax",
+		Param:   strconv.Itoa(p.pos),
+		Value:   p.input,
+		Message: fmt.Sprintf("filter: %s at %d", fmt.Sprintf(format, args...), p.pos),
+	}
+}
+
+func isSelector(c byte) bool {
+	return c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type queryItem struct {
+	ID        string    `json:"id" bson:"_id"`
+	Href      string    `json:"href" bson:"-"`
+	Name      string    `json:"name"`
+	Price     float64   `json:"price"`
+	Quantity  int       `json:"quantity"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var querySchema = NewQuerySchema(queryItem{})
+
+func parseQuery(t *testing.T, query string) (FindOption, error) {
+	values, err := url.ParseQuery(strings.ReplaceAll(query, ";", "%3B"))
+	require.NoError(t, err)
+	return querySchema.Parse(values.Get)
+}
+
+func TestQuerySchemaParse(t *testing.T) {
+	option, err := parseQuery(t, "filter=price=gt=10;quantity=ge=1&sort=-price,name&page=2&limit=20&fields=name,price")
+	require.NoError(t, err)
+	assert.Equal(t, FindOption{
+		Filter: []Filter{{And: []Filter{
+			{Key: "price", Operator: Gt, Value: 10.0},
+			{Key: "quantity", Operator: Gte, Value: int64(1)},
+		}}},
+		Sort:       map[string]SortDirection{"price": DESC, "name": ASC},
+		Order:      []string{"price", "name"},
+		Page:       2,
+		Limit:      20,
+		Projection: "name,price",
+	}, option)
+
+	keys, _ := sortKeys([]FindOption{option})
+	assert.Equal(t, []string{"price", "name"}, keys)
+}
+
+func TestQuerySchemaParseFilter(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for filter, want := range map[string]Filter{
+		"name==Banana":                       {Key: "name", Operator: Eq, Value: "Banana"},
+		"name==*phone*":                      {Key: "name", Operator: Like, Value: "%phone%"},
+		`name==50%_off\*`:                    {Key: "name", Operator: Like, Value: `50\%\_off\\%`},
+		"name!='a;b'":                        {Key: "name", Operator: Ne, Value: "a;b"},
+		"active==true":                       {Key: "active", Operator: Eq, Value: true},
+		"id=in=(1,\"2\")":                    {Key: "id", Operator: In, Value: []interface{}{"1", "2"}},
+		"quantity=out=(0)":                   {Key: "quantity", Operator: Nin, Value: []interface{}{int64(0)}},
+		"name=regex=^Ch":                     {Key: "name", Operator: Regex, Value: "^Ch"},
+		"price=exists=false":                 {Key: "price", Operator: Exists, Value: false},
+		"created_at=lt=2024-01-02T03:04:05Z": {Key: "created_at", Operator: Lt, Value: created},
+		"price=lt=5,quantity==0;active==true": {Or: []Filter{
+			{Key: "price", Operator: Lt, Value: 5.0},
+			{And: []Filter{
+				{Key: "quantity", Operator: Eq, Value: int64(0)},
+				{Key: "active", Operator: Eq, Value: true},
+			}},
+		}},
+		"(price=lt=5,quantity==0);active==true": {And: []Filter{
+			{Or: []Filter{
+				{Key: "price", Operator: Lt, Value: 5.0},
+				{Key: "quantity", Operator: Eq, Value: int64(0)},
+			}},
+			{Key: "active", Operator: Eq, Value: true},
+		}},
+	} {
+		option, err := querySchema.Parse(url.Values{"filter": {filter}}.Get)
+		if assert.NoError(t, err, filter) {
+			assert.Equal(t, []Filter{want}, option.Filter, filter)
+		}
+	}
+}
+
+func TestQuerySchemaParseErrors(t *testing.T) {
+	for query, tags := range map[string][]string{
+		"filter=price=gt=ten":             {"value"},
+		"filter=secret==1":                {"field"},
+		"filter=href==x":                  {"field"},
+		"filter=price=between=1":          {"operator"},
+		"filter=price=like=1":             {"value"},
+		"filter=price":                    {"syntax"},
+		"filter=(price=gt=1":              {"syntax"},
+		"filter=id=in=1":                  {"syntax"},
+		"filter=name=='abc":               {"syntax"},
+		"filter=price=gt=1)":              {"syntax"},
+		"sort=-secret&fields=name,secret": {"field", "field"},
+		"page=0&limit=1000":               {"gte", "max"},
+		"page=x&limit=x&filter=price==x":  {"value", "gte", "max"},
+	} {
+		_, err := parseQuery(t, query)
+		var queryErr *QueryError
+		if assert.ErrorAs(t, err, &queryErr, query) {
+			var got []string
+			for _, e := range queryErr.Errors {
+				got = append(got, e.Tag)
+			}
+			assert.Equal(t, tags, got, query)
+		}
+	}
+}
+
+func TestQuerySchemaOperators(t *testing.T) {
+	schema := NewQuerySchema(queryItem{}).Operators("name", Eq, Like)
+
+	for filter, allowed := range map[string]bool{
+		"name==Banana":   true,
+		"name==*phone*":  true,
+		"name!=Banana":   false,
+		"name=regex=^Ch": false,
+		"price=gt=1":     true,
+		"id=regex=^1":    true,
+	} {
+		_, err := schema.Parse(url.Values{"filter": {filter}}.Get)
+		if allowed {
+			assert.NoError(t, err, filter)
+			continue
+		}
+		var queryErr *QueryError
+		if assert.ErrorAs(t, err, &queryErr, filter) {
+			assert.Equal(t, "operator", queryErr.Errors[0].Tag, filter)
+			assert.Equal(t, "name", queryErr.Errors[0].Param, filter)
+		}
+	}
+
+	schema = NewQuerySchema(queryItem{}).Operators("name", Eq)
+	_, err := schema.Parse(url.Values{"filter": {"name==*phone*"}}.Get)
+	assert.Error(t, err)
+}
+
+func TestNewQuerySchemaPanics(t *testing.T) {
+	assert.Panics(t, func() { NewQuerySchema(queryItem{}, "secret") })
+	assert.Panics(t, func() { NewQuerySchema(queryItem{}, "name").Operators("price", Eq) })
+}
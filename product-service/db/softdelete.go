@@ -0,0 +1,61 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// ErrEmptyFilter is the error of Update, Delete, Restore and HardDelete
+// without a filter, which would change every record.
+var ErrEmptyFilter = errors.New("db: empty filter")
+
+// ErrNoSoftDelete is the error of Restore on a model without a DeletedAt field.
+var ErrNoSoftDelete = errors.New("db: model has no DeletedAt field")
+
+// deletedAt is the DeletedAt field of T, a *time.Time or a gorm.DeletedAt,
+// ok is false when T is not soft deleted.
+func deletedAt[T any]() (field reflect.StructField, ok bool) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return field, false
+	}
+	return t.FieldByName("DeletedAt")
+}
+
+// bsonName is the name of field in a bson document.
+func bsonName(field reflect.StructField) string {
+	if name := strings.SplitN(field.Tag.Get("bson"), ",", 2)[0]; name != "" {
+		return name
+	}
+	return strings.ToLower(field.Name)
+}
+
+// withDeleted reports whether findOption finds the soft deleted records.
+func withDeleted(findOption []FindOption) bool {
+	for _, option := range findOption {
+		if option.WithDeleted {
+			return true
+		}
+	}
+	return false
+}
+
+// notDeleted adds to filters the condition on the DeletedAt field key that
+// skips the soft deleted records.
+func notDeleted(filters []Filter, key string) []Filter {
+	return append(filters[:len(filters):len(filters)], Filter{Key: key, Value: nil})
+}
+
+// writeFilters converts the filter of a write like toFilters, refusing an
+// empty one.
+func writeFilters(filter interface{}) ([]Filter, error) {
+	filters, err := toFilters(filter)
+	if err == nil && len(filters) == 0 {
+		err = ErrEmptyFilter
+	}
+	return filters, err
+}
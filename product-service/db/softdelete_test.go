@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type softItem struct {
+	ID        string     `gorm:"primaryKey" bson:"_id"`
+	Name      string     `bson:"name"`
+	DeletedAt *time.Time `bson:"deleted_at,omitempty"`
+}
+
+// testSoftDelete runs the soft delete cases against store.
+func testSoftDelete(t *testing.T, store DataStore[softItem]) {
+	ctx := context.Background()
+	for _, id := range []string{"1", "2", "3"} {
+		require.NoError(t, store.Create(ctx, softItem{ID: id, Name: "item " + id}).Err)
+	}
+
+	deleted := store.Delete(ctx, Filter{Key: "id", Operator: In, Value: []string{"1", "2"}})
+	require.NoError(t, deleted.Err, deleted.Raw)
+	assert.Equal(t, int64(2), deleted.Count)
+	// deleting again is a no-op
+	assert.Equal(t, int64(0), store.Delete(ctx, map[string]interface{}{"id": "1"}).Count)
+
+	assert.Equal(t, int64(1), store.Count(ctx).Count)
+	found := store.Find(ctx)
+	require.NoError(t, found.Err)
+	require.Len(t, found.Data, 1)
+	assert.Equal(t, "3", found.Data[0].ID)
+	assert.Error(t, store.FindOne(ctx, FindOption{Filter: []Filter{{Key: "id", Value: "1"}}}).Err)
+	assert.Equal(t, int64(0), store.Update(ctx, map[string]interface{}{"id": "1"}, softItem{Name: "x"}).Count)
+
+	all := store.Find(ctx, FindOption{WithDeleted: true, Sort: map[string]SortDirection{"id": ASC}})
+	require.NoError(t, all.Err)
+	assert.Len(t, all.Data, 3)
+	assert.NotNil(t, all.Data[0].DeletedAt)
+	one := store.FindOne(ctx, FindOption{Filter: []Filter{{Key: "id", Value: "1"}}, WithDeleted: true})
+	require.NoError(t, one.Err)
+	assert.Equal(t, "item 1", one.Data.Name)
+
+	restored := store.Restore(ctx, map[string]interface{}{"id": "1"})
+	require.NoError(t, restored.Err, restored.Raw)
+	assert.Equal(t, int64(1), restored.Count)
+	assert.Equal(t, int64(2), store.Count(ctx).Count)
+	one = store.FindOne(ctx, FindOption{Filter: []Filter{{Key: "id", Value: "1"}}})
+	require.NoError(t, one.Err)
+	assert.Nil(t, one.Data.DeletedAt)
+
+	// the soft deleted record 2 is removed too
+	removed := store.HardDelete(ctx, Filter{Key: "id", Operator: In, Value: []string{"2", "3"}})
+	require.NoError(t, removed.Err, removed.Raw)
+	assert.Equal(t, int64(2), removed.Count)
+	assert.Equal(t, int64(1), store.Count(ctx, FindOption{WithDeleted: true}).Count)
+
+	assert.ErrorIs(t, store.Delete(ctx, nil).Err, ErrEmptyFilter)
+	assert.ErrorIs(t, store.HardDelete(ctx, map[string]interface{}{}).Err, ErrEmptyFilter)
+
+	// the soft delete scope is no filter, nothing is updated
+	assert.ErrorIs(t, store.Update(ctx, nil, softItem{Name: "zzz"}).Err, ErrEmptyFilter)
+	assert.ErrorIs(t, store.Update(ctx, []Filter{}, softItem{Name: "zzz"}).Err, ErrEmptyFilter)
+	written := store.BulkWrite(ctx, []WriteOp[softItem]{{Kind: UpdateWrite, Model: softItem{Name: "zzz"}}})
+	assert.ErrorIs(t, written.Data.Items[0].Err, ErrEmptyFilter)
+	assert.Equal(t, int64(0), store.Count(ctx, FindOption{Filter: []Filter{{Key: "name", Value: "zzz"}}}).Count)
+}
+
+// testHardDelete deletes records of a model without DeletedAt.
+func testHardDelete(t *testing.T, store DataStore[filterItem]) {
+	ctx := context.Background()
+	for _, item := range filterItems {
+		require.NoError(t, store.Create(ctx, item).Err)
+	}
+
+	deleted := store.Delete(ctx, Filter{Key: "price", Operator: Lt, Value: 100})
+	require.NoError(t, deleted.Err, deleted.Raw)
+	assert.Equal(t, int64(2), deleted.Count)
+	assert.Equal(t, int64(2), store.Count(ctx, FindOption{WithDeleted: true}).Count)
+	assert.ErrorIs(t, store.Restore(ctx, map[string]interface{}{"id": "3"}).Err, ErrNoSoftDelete)
+}
+
+func TestSoftDeleteGorm(t *testing.T) {
+	testSoftDelete(t, newSQLiteStore[softItem](t))
+	testHardDelete(t, newSQLiteStore[filterItem](t))
+}
+
+func TestSoftDeleteMongo(t *testing.T) {
+	testSoftDelete(t, newMongoStore[softItem](t))
+	testHardDelete(t, newMongoStore[filterItem](t))
+}
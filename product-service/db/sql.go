@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	gosqlite "github.com/glebarez/go-sqlite"
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type SQLConfig struct {
+	// Driver is postgres (default), mysql or sqlite.
+	Driver string
+	// DSN is the connection string of the driver, the file of the database
+	// for sqlite.
+	DSN          string
+	MaxOpenConns int
+	MaxIdleConns int
+	// MaxIdleTime closes the connections idle for longer, MaxLifetime those
+	// open for longer, never when zero.
+	MaxIdleTime time.Duration
+	MaxLifetime time.Duration
+	// Logger logs the queries, default the warnings of gorm.
+	Logger logger.Interface
+}
+
+type SQLClient struct {
+	db     *gorm.DB
+	config SQLConfig
+}
+
+// NewSQLClient connects to the database of config, the connections are shared
+// by the stores of NewGormDB.
+func NewSQLClient(config SQLConfig) (*SQLClient, error) {
+	dialector, err := sqlDialector(config)
+	if err != nil {
+		return nil, err
+	}
+
+	log := config.Logger
+	if log == nil {
+		log = logger.Default.LogMode(logger.Warn)
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: log})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	sqlDB.SetConnMaxIdleTime(config.MaxIdleTime)
+	sqlDB.SetConnMaxLifetime(config.MaxLifetime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := sqlDB.PingContext(ctx); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	return &SQLClient{
+		db:     db,
+		config: config,
+	}, nil
+}
+
+func sqlDialector(config SQLConfig) (gorm.Dialector, error) {
+	switch config.Driver {
+	case "", "postgres", "postgresql":
+		return postgres.Open(config.DSN), nil
+	case "mysql":
+		return mysql.Open(config.DSN), nil
+	case "sqlite", "sqlite3":
+		registerSQLiteRegexp()
+		return sqlite.Open(config.DSN), nil
+	}
+	return nil, fmt.Errorf("unsupported sql driver %q", config.Driver)
+}
+
+var sqliteRegexp sync.Once
+
+// registerSQLiteRegexp implements the REGEXP operator of SQLite, which has
+// none of its own, for the Regex filters. x REGEXP y calls regexp(y, x).
+func registerSQLiteRegexp() {
+	sqliteRegexp.Do(func() {
+		gosqlite.MustRegisterDeterministicScalarFunction("regexp", 2, func(_ *gosqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			pattern, _ := args[0].(string)
+			value, _ := args[1].(string)
+			return regexp.MatchString(pattern, value)
+		})
+	})
+}
+
+// Migrate creates or updates the tables of models, to run once at start up
+// rather than for every store.
+func (c *SQLClient) Migrate(models ...interface{}) error {
+	return c.db.AutoMigrate(models...)
+}
+
+func (c *SQLClient) Close() error {
+	sqlDB, err := c.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// NewGormDB is the store of T in the database of client.
+func NewGormDB[T any](client *SQLClient) DataStore[T] {
+	return &gormDb[T]{
+		db: client.db,
+	}
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSQLClient(t *testing.T) {
+	_, err := NewSQLClient(SQLConfig{Driver: "oracle"})
+	assert.EqualError(t, err, `unsupported sql driver "oracle"`)
+
+	client, err := NewSQLClient(SQLConfig{
+		Driver:       "sqlite",
+		DSN:          filepath.Join(t.TempDir(), "client.db"),
+		MaxOpenConns: 3,
+		MaxIdleTime:  time.Minute,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+	require.NoError(t, client.Migrate(&filterItem{}))
+
+	sqlDB, err := client.db.DB()
+	require.NoError(t, err)
+	assert.Equal(t, 3, sqlDB.Stats().MaxOpenConnections)
+
+	// the stores share the connections of the client
+	items := NewGormDB[filterItem](client)
+	require.NoError(t, items.Create(context.Background(), filterItems[0]).Err)
+	assert.Equal(t, int64(1), NewGormDB[filterItem](client).Count(context.Background()).Count)
+	assert.Same(t, client.db, items.(*gormDb[filterItem]).db)
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"gorm.io/gorm"
+)
+
+// Tx is the context of a transaction, the stores of its client called with
+// it, and the repositories on them, run in the transaction.
+//
+//	err := db.Transaction(ctx, client, func(tx db.Tx) error {
+//		if err := products.Update(tx, db.Filter{Key: "id", Value: id}, product).Err; err != nil {
+//			return err
+//		}
+//		return audits.Create(tx, audit).Err
+//	})
+type Tx interface {
+	context.Context
+	// Transaction runs fn in a nested transaction, a savepoint on SQL
+	// databases rolled back alone when fn fails. Mongo has no savepoints, fn
+	// runs in the transaction itself.
+	Transaction(fn func(tx Tx) error) error
+}
+
+// TxClient is a client Transaction begins the transactions of, a *SQLClient
+// or a *MongoClient.
+type TxClient interface {
+	transaction(ctx context.Context, fn func(tx Tx) error) error
+}
+
+// Transaction runs fn in a transaction of client, committed when fn returns
+// nil and rolled back when it returns an error or panics. With ctx in a
+// transaction of client, fn runs in a nested transaction.
+//
+// Mongo transactions need a replica set, and run fn again on a transient
+// error, fn should not have other side effects.
+func Transaction(ctx context.Context, client TxClient, fn func(tx Tx) error) error {
+	return client.transaction(ctx, fn)
+}
+
+// sqlTxKey is the context key of the transaction of the client db.
+type sqlTxKey struct {
+	db *gorm.DB
+}
+
+type sqlTx struct {
+	context.Context
+	client *gorm.DB
+}
+
+// sqlTransaction runs fn in a transaction of client, in a savepoint when ctx
+// is in a transaction of client already.
+func sqlTransaction(ctx context.Context, client *gorm.DB, fn func(tx Tx) error) error {
+	db, ok := ctx.Value(sqlTxKey{client}).(*gorm.DB)
+	if !ok {
+		db = client
+	}
+	return db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		return fn(&sqlTx{Context: context.WithValue(ctx, sqlTxKey{client}, db), client: client})
+	})
+}
+
+func (tx *sqlTx) Transaction(fn func(tx Tx) error) error {
+	return sqlTransaction(tx, tx.client, fn)
+}
+
+func (c *SQLClient) transaction(ctx context.Context, fn func(tx Tx) error) error {
+	return sqlTransaction(ctx, c.db, fn)
+}
+
+// mongoTxKey marks the context of a transaction of the client db.
+type mongoTxKey struct {
+	db *mongo.Client
+}
+
+type mongoTx struct {
+	context.Context
+}
+
+func (tx *mongoTx) Transaction(fn func(tx Tx) error) error {
+	return fn(tx)
+}
+
+func (c *MongoClient) transaction(ctx context.Context, fn func(tx Tx) error) error {
+	if ctx.Value(mongoTxKey{c.db}) != nil {
+		return fn(&mongoTx{Context: ctx})
+	}
+
+	sess, err := c.db.StartSession()
+	if err != nil {
+		return err
+	}
+	defer sess.EndSession(ctx)
+
+	// the session of ctx binds the collections of the stores to the
+	// transaction
+	_, err = sess.WithTransaction(ctx, func(ctx context.Context) (interface{}, error) {
+		return nil, fn(&mongoTx{Context: context.WithValue(ctx, mongoTxKey{c.db}, true)})
+	})
+	return err
+}
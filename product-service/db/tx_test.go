@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var errRollback = errors.New("rollback")
+
+// testTransaction runs the transaction cases against two stores of client,
+// the nested ones when it has savepoints.
+func testTransaction(t *testing.T, client TxClient, items DataStore[softItem], bulk DataStore[bulkItem], savepoints bool) {
+	ctx := context.Background()
+
+	err := Transaction(ctx, client, func(tx Tx) error {
+		if err := items.Create(tx, softItem{ID: "1", Name: "item 1"}).Err; err != nil {
+			return err
+		}
+		// the page and the count are read in the transaction
+		page := items.FindAndCount(tx)
+		if page.Err != nil {
+			return page.Err
+		}
+		assert.Equal(t, int64(1), page.Count)
+		assert.Len(t, page.Data, 1)
+		return bulk.Create(tx, bulkItems("1")[0]).Err
+	})
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorCode(20) {
+		t.Skip("transactions need a replica set")
+	}
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), items.Count(ctx).Count)
+	assert.Equal(t, int64(1), bulk.Count(ctx).Count)
+
+	err = Transaction(ctx, client, func(tx Tx) error {
+		require.NoError(t, items.Update(tx, Filter{Key: "id", Value: "1"}, softItem{Name: "changed"}).Err)
+		require.NoError(t, bulk.Create(tx, bulkItems("2")[0]).Err)
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+	assert.Equal(t, "item 1", items.FindOne(ctx).Data.Name)
+	assert.Equal(t, int64(1), bulk.Count(ctx).Count)
+
+	assert.Panics(t, func() {
+		Transaction(ctx, client, func(tx Tx) error {
+			require.NoError(t, items.Delete(tx, Filter{Key: "id", Value: "1"}).Err)
+			panic("panic")
+		})
+	})
+	assert.Equal(t, int64(1), items.Count(ctx).Count)
+
+	if !savepoints {
+		return
+	}
+
+	err = Transaction(ctx, client, func(tx Tx) error {
+		require.NoError(t, items.Create(tx, softItem{ID: "2", Name: "item 2"}).Err)
+		err := tx.Transaction(func(tx Tx) error {
+			require.NoError(t, items.Create(tx, softItem{ID: "3", Name: "item 3"}).Err)
+			return errRollback
+		})
+		assert.ErrorIs(t, err, errRollback)
+		// the transaction of ctx nests too
+		require.NoError(t, Transaction(tx, client, func(tx Tx) error {
+			return items.Create(tx, softItem{ID: "4", Name: "item 4"}).Err
+		}))
+
+		// the duplicate is rolled back alone
+		created := bulk.CreateMany(tx, bulkItems("1", "5"))
+		assert.Equal(t, []int{0}, created.Data.Failed())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"1": "item 1", "5": "item 5"}, names(t, bulk))
+	found := items.Find(ctx, FindOption{Sort: map[string]SortDirection{"id": ASC}})
+	require.NoError(t, found.Err)
+	var ids []string
+	for _, item := range found.Data {
+		ids = append(ids, item.ID)
+	}
+	assert.Equal(t, []string{"1", "2", "4"}, ids)
+}
+
+func TestTransactionGorm(t *testing.T) {
+	client, err := NewSQLClient(SQLConfig{
+		Driver: "sqlite",
+		DSN:    filepath.Join(t.TempDir(), "tx.db"),
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	require.NoError(t, client.Migrate(new(softItem), new(bulkItem)))
+
+	// the queries of a transaction share its connection, they must not
+	// overlap
+	var running, overlapped int32
+	queries := client.db.Callback().Query()
+	require.NoError(t, queries.Before("gorm:query").Register("test:before", func(*gorm.DB) {
+		if atomic.AddInt32(&running, 1) > 1 {
+			atomic.StoreInt32(&overlapped, 1)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}))
+	require.NoError(t, queries.After("gorm:query").Register("test:after", func(*gorm.DB) {
+		atomic.AddInt32(&running, -1)
+	}))
+	t.Cleanup(func() { assert.Zero(t, atomic.LoadInt32(&overlapped)) })
+
+	testTransaction(t, client, NewGormDB[softItem](client), NewGormDB[bulkItem](client), true)
+}
+
+// TestTransactionMongo runs against the replica set of MONGO_URI, skipped
+// when not set.
+func TestTransactionMongo(t *testing.T) {
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		t.Skip("MONGO_URI is not set")
+	}
+	client, err := NewMongoClient(MongoConfig{URI: uri, Database: "db_test"})
+	require.NoError(t, err)
+
+	items := NewMongoDB(softItem{}, client).(*mongDb[softItem])
+	bulk := NewMongoDB(bulkItem{}, client).(*mongDb[bulkItem])
+	for _, coll := range []*mongo.Collection{items.db, bulk.db} {
+		require.NoError(t, coll.Drop(context.Background()))
+		t.Cleanup(func() { coll.Drop(context.Background()) })
+	}
+
+	testTransaction(t, client, items, bulk, false)
+}
@@ -1,6 +1,15 @@
 package handler
 
 import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/sing3demons/product-service/db"
 	"github.com/sing3demons/product-service/model"
 	"github.com/sing3demons/product-service/ms"
 	"github.com/sing3demons/product-service/service"
@@ -10,21 +19,79 @@ type ProductHandler interface {
 	GetProducts(ctx ms.IContext) error
 	CreateProduct(ctx ms.IContext) error
 	GetProduct(ctx ms.IContext) error
+	StreamProducts(ctx ms.IContext) error
+	ForwardEvent(ctx ms.IContext) error
+	ProductSocket(ctx ms.WSContext) error
+}
+
+// ProductsQuery lists the query parameters of GetProducts, see
+// db.QuerySchema.Parse for the filter and sort syntax, =regex= is refused.
+type ProductsQuery struct {
+	Search string `query:"search"`
+	Filter string `query:"filter"`
+	Sort   string `query:"sort"`
+	Fields string `query:"fields"`
+	Page   int    `query:"page"`
+	Limit  int    `query:"limit"`
+}
+
+// productQuery lists the fields GetProducts may filter, sort and select,
+// the text fields without =regex=.
+var productQuery = db.NewQuerySchema(model.Product{}, "id", "name", "description", "price", "quantity").
+	Operators("id", textOperators...).
+	Operators("name", textOperators...).
+	Operators("description", textOperators...)
+
+var textOperators = []db.Operator{db.Eq, db.Ne, db.In, db.Nin, db.Like, db.Exists}
+
+// ProductStreamQuery lists the query parameters of StreamProducts, it is also
+// the message ProductSocket clients send to change their filter.
+type ProductStreamQuery struct {
+	Type     string  `query:"type" json:"type,omitempty"`
+	Name     string  `query:"name" json:"name,omitempty"`
+	MinPrice float64 `query:"min_price" json:"min_price,omitempty"`
+	MaxPrice float64 `query:"max_price" json:"max_price,omitempty"`
+}
+
+// ProductEventsTopic is the event bus and Kafka topic of product changes.
+const ProductEventsTopic = "product.events"
+
+// sourceHeader is the message header of the instance a product event comes
+// from, its instanceID.
+const sourceHeader = "X-Source-Instance"
+
+var instanceID = uuid.New().String()
+
+const (
+	ProductCreated = "created"
+	ProductUpdated = "updated"
+)
+
+type ProductEvent struct {
+	Type    string        `json:"type"`
+	Product model.Product `json:"product"`
 }
 
 type productHandler struct {
 	service service.ProductService
+	events  ms.EventBus
 }
 
-func NewProductHandler(service service.ProductService) ProductHandler {
-	return &productHandler{service: service}
+func NewProductHandler(service service.ProductService, events ms.EventBus) ProductHandler {
+	return &productHandler{service: service, events: events}
 }
 
 func (h *productHandler) GetProducts(ctx ms.IContext) error {
-	fields := ctx.Query("fields")
-	filter := ctx.Query("search")
+	options, err := productQuery.Parse(ctx.Query)
+	if err != nil {
+		ctx.Response(http.StatusBadRequest, err)
+		return nil
+	}
+	if search := ctx.Query("search"); search != "" {
+		options.Filter = append(options.Filter, db.Filter{Key: "name", Value: search})
+	}
 
-	result := h.service.Find(filter, fields)
+	result := h.service.Find(ctx.Context(), options)
 	ctx.Response(result.Status, result)
 	return nil
 }
@@ -33,18 +100,36 @@ func (h *productHandler) CreateProduct(ctx ms.IContext) error {
 	product := model.Product{}
 	err := ctx.ReadInput(&product)
 	if err != nil {
-		ctx.Response(400, err)
+		var validationErr *ms.ValidationError
+		if errors.As(err, &validationErr) {
+			ctx.Response(http.StatusBadRequest, validationErr)
+			return nil
+		}
+		status := http.StatusBadRequest
+		var coder ms.StatusCoder
+		if errors.As(err, &coder) {
+			status = coder.StatusCode()
+		}
+		ctx.Response(status, service.Response{
+			Status:  status,
+			Message: err.Error(),
+		})
 		return nil
 	}
 
-	result := h.service.Create(product)
+	result := h.service.Create(ctx.Context(), product)
+	if result.Success {
+		for _, p := range result.Data {
+			publishEvent(ctx, h.events, ProductEvent{Type: ProductCreated, Product: p})
+		}
+	}
 	ctx.Response(result.Status, result)
 	return nil
 }
 
 func (h *productHandler) GetProduct(ctx ms.IContext) error {
 	id := ctx.Param("id")
-	result := h.service.FindOne(id)
+	result := h.service.FindOne(ctx.Context(), id)
 	if result.Status == 404 {
 		ctx.Response(404, result)
 		return nil
@@ -52,3 +137,145 @@ func (h *productHandler) GetProduct(ctx ms.IContext) error {
 	ctx.Response(result.Status, result)
 	return nil
 }
+
+// StreamProducts sends the product events matching the query as Server-Sent Events.
+func (h *productHandler) StreamProducts(ctx ms.IContext) error {
+	query := ProductStreamQuery{
+		Type: ctx.Query("type"),
+		Name: strings.ToLower(ctx.Query("name")),
+	}
+	for name, price := range map[string]*float64{"min_price": &query.MinPrice, "max_price": &query.MaxPrice} {
+		value := ctx.Query(name)
+		if value == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			ctx.Response(http.StatusBadRequest, service.Response{
+				Status:  http.StatusBadRequest,
+				Message: "invalid " + name,
+			})
+			return nil
+		}
+		*price = v
+	}
+
+	events, cancel := h.events.Subscribe(ProductEventsTopic)
+	defer cancel()
+
+	return ctx.Stream(func(w ms.SSEWriter) error {
+		for {
+			select {
+			case <-w.Done():
+				return nil
+			case e := <-events:
+				event, ok := e.Data.(ProductEvent)
+				if !ok || !query.match(event) {
+					continue
+				}
+				if err := w.Send(ms.SSEEvent{ID: event.Product.ID, Event: event.Type, Data: event.Product}); err != nil {
+					return err
+				}
+			}
+		}
+	})
+}
+
+// ProductSocket sends the product events to a WebSocket client, the client
+// sends a ProductStreamQuery to filter them.
+func (h *productHandler) ProductSocket(ctx ms.WSContext) error {
+	events, cancel := h.events.Subscribe(ProductEventsTopic)
+	defer cancel()
+
+	var mu sync.Mutex
+	var query ProductStreamQuery
+	closed := make(chan error, 1)
+	go func() {
+		for {
+			var q ProductStreamQuery
+			err := ctx.ReadJSON(&q)
+			var bindErr *ms.BindError
+			if errors.As(err, &bindErr) {
+				ctx.WriteJSON(service.Response{Status: http.StatusBadRequest, Message: err.Error()})
+				continue
+			}
+			if err != nil {
+				closed <- err
+				return
+			}
+
+			q.Name = strings.ToLower(q.Name)
+			mu.Lock()
+			query = q
+			mu.Unlock()
+		}
+	}()
+
+	for {
+		select {
+		case err := <-closed:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case <-ctx.Done():
+			return nil
+		case e := <-events:
+			event, ok := e.Data.(ProductEvent)
+			mu.Lock()
+			match := ok && query.match(event)
+			mu.Unlock()
+			if !match {
+				continue
+			}
+			if err := ctx.WriteJSON(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// publishEvent publishes event on the event bus and sends it to the other
+// instances, which forward it with ForwardEvent. A failed send is logged, the
+// change is done.
+func publishEvent(ctx ms.IContext, events ms.EventBus, event ProductEvent) {
+	events.Publish(ProductEventsTopic, event)
+	if ctx == nil {
+		return
+	}
+	err := ctx.SendMessage(ProductEventsTopic, event, ms.WithHeader(sourceHeader, instanceID))
+	if err != nil && !errors.Is(err, ms.ErrBrokerNotSet) {
+		ctx.Log("product event not sent: " + err.Error())
+	}
+}
+
+// ForwardEvent publishes a product event of another instance read from Kafka
+// on the event bus, the events of this instance were published already.
+func (h *productHandler) ForwardEvent(ctx ms.IContext) error {
+	if ctx.Header(sourceHeader) == instanceID {
+		return nil
+	}
+
+	var event ProductEvent
+	if err := ctx.ReadInput(&event); err != nil {
+		return err
+	}
+	h.events.Publish(ProductEventsTopic, event)
+	return nil
+}
+
+func (q ProductStreamQuery) match(event ProductEvent) bool {
+	if q.Type != "" && q.Type != event.Type {
+		return false
+	}
+	if q.Name != "" && !strings.Contains(strings.ToLower(event.Product.Name), q.Name) {
+		return false
+	}
+	if q.MinPrice > 0 && event.Product.Price < q.MinPrice {
+		return false
+	}
+	if q.MaxPrice > 0 && event.Product.Price > q.MaxPrice {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/sing3demons/product-service/db"
+	"github.com/sing3demons/product-service/model"
+	"github.com/sing3demons/product-service/ms"
+	"github.com/sing3demons/product-service/proto/productpb"
+	"github.com/sing3demons/product-service/service"
+)
+
+type productGRPCServer struct {
+	productpb.UnimplementedProductServiceServer
+	service service.ProductService
+	events  ms.EventBus
+}
+
+// NewProductGRPCServer exposes the product service over gRPC, errors are
+// returned as ms errors and mapped to gRPC codes by the server.
+func NewProductGRPCServer(service service.ProductService, events ms.EventBus) productpb.ProductServiceServer {
+	return &productGRPCServer{service: service, events: events}
+}
+
+func (s *productGRPCServer) GetProduct(ctx context.Context, req *productpb.GetProductRequest) (*productpb.Product, error) {
+	result := s.service.FindOne(ctx, req.GetId())
+	if !result.Success {
+		return nil, ms.NewError(result.Status, result.Message)
+	}
+	return toProto(result.Data), nil
+}
+
+func (s *productGRPCServer) ListProducts(req *productpb.ListProductsRequest, stream productpb.ProductService_ListProductsServer) error {
+	options := db.FindOption{Projection: req.GetFields(), Limit: db.MaxLimit}
+	if search := req.GetSearch(); search != "" {
+		options.Filter = []db.Filter{{Key: "name", Value: search}}
+	}
+
+	// the stream holds every product, page after page
+	for page := 1; ; page++ {
+		options.Page = page
+		result := s.service.Find(stream.Context(), options)
+		if !result.Success {
+			return ms.NewError(result.Status, result.Message)
+		}
+
+		for _, product := range result.Data {
+			if err := stream.Send(toProto(product)); err != nil {
+				return err
+			}
+		}
+		if result.Page == nil || page >= result.Page.TotalPages {
+			return nil
+		}
+	}
+}
+
+func (s *productGRPCServer) CreateProduct(ctx context.Context, req *productpb.CreateProductRequest) (*productpb.Product, error) {
+	product := model.Product{
+		Name:        req.GetName(),
+		Description: req.GetDescription(),
+		Price:       req.GetPrice(),
+		Quantity:    int(req.GetQuantity()),
+	}
+	if err := ms.Validate(&product); err != nil {
+		return nil, err
+	}
+
+	result := s.service.Create(ctx, product)
+	if !result.Success {
+		return nil, ms.NewError(result.Status, result.Message)
+	}
+
+	created := result.Data[0]
+	publishEvent(ms.GRPCContextFrom(ctx), s.events, ProductEvent{Type: ProductCreated, Product: created})
+	return toProto(created), nil
+}
+
+func toProto(product model.Product) *productpb.Product {
+	return &productpb.Product{
+		Id:          product.ID,
+		Href:        product.Href,
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       product.Price,
+		Quantity:    int64(product.Quantity),
+	}
+}
@@ -0,0 +1,39 @@
+package handler_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sing3demons/product-service/handler"
+	"github.com/sing3demons/product-service/model"
+	"github.com/sing3demons/product-service/ms"
+	"github.com/sing3demons/product-service/proto/productpb"
+	"github.com/sing3demons/product-service/repository"
+	"github.com/sing3demons/product-service/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductGRPCCreate(t *testing.T) {
+	productRepositoryMock := repository.NewProductRepositoryMock()
+	// the store sets the id, like Mongo does
+	productRepositoryMock.On("Create", mock.Anything, mock.Anything).Return(func(product model.Product) model.Product {
+		product.ID = "generated"
+		return product
+	}, nil)
+
+	events := ms.NewEventBus(1)
+	created, cancel := events.Subscribe(handler.ProductEventsTopic)
+	defer cancel()
+
+	server := handler.NewProductGRPCServer(service.NewProductService(productRepositoryMock), events)
+	product, err := server.CreateProduct(context.Background(), &productpb.CreateProductRequest{Name: "Product 1", Price: 100, Quantity: 10})
+	require.NoError(t, err)
+	assert.NotEmpty(t, product.GetId())
+	assert.Equal(t, "generated", product.GetId())
+	assert.Equal(t, "Product 1", product.GetName())
+
+	event := (<-created).Data.(handler.ProductEvent)
+	assert.Equal(t, "generated", event.Product.ID)
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/sing3demons/product-service/config"
@@ -10,22 +14,76 @@ import (
 	"github.com/sing3demons/product-service/handler"
 	"github.com/sing3demons/product-service/model"
 	"github.com/sing3demons/product-service/ms"
+	"github.com/sing3demons/product-service/proto/productpb"
 	"github.com/sing3demons/product-service/repository"
 	"github.com/sing3demons/product-service/service"
 )
 
 type DbConfig struct {
+	// Addr is the DSN of the SQL database of Driver, postgres, mysql or sqlite.
 	Addr         string `env:"DATABASE_DSN" yaml:"addr" json:"addr"`
 	Uri          string `env:"DATABASE_URI" yaml:"uri" json:"uri"`
 	MaxOpenConns int    `env:"DB_MAX_OPEN_CONNS" default:"10" yaml:"max_open_conns" json:"max_open_conns"`
 	MaxIdleConns int    `env:"DB_MAX_IDLE_CONNS" default:"1" yaml:"max_idle_conns" json:"max_idle_conns"`
-	MaxIdleTime  int    `default:"10" yaml:"max_idle_time" json:"max_idle_time"`
-	Driver       string `env:"DB_DRIVER"`
+	// MaxIdleTime is a duration, e.g. 10m.
+	MaxIdleTime string `env:"DB_MAX_IDLE_TIME" default:"10m" yaml:"max_idle_time" json:"max_idle_time"`
+	Driver      string `env:"DB_DRIVER" yaml:"driver" json:"driver"`
+}
+
+// sqlClient connects to the SQL database of cfg.
+func sqlClient(cfg DbConfig) (*db.SQLClient, error) {
+	idle, err := time.ParseDuration(cfg.MaxIdleTime)
+	if err != nil {
+		return nil, fmt.Errorf("db max_idle_time: %w", err)
+	}
+	return db.NewSQLClient(db.SQLConfig{
+		Driver:       cfg.Driver,
+		DSN:          cfg.Addr,
+		MaxOpenConns: cfg.MaxOpenConns,
+		MaxIdleConns: cfg.MaxIdleConns,
+		MaxIdleTime:  idle,
+	})
+}
+
+type AuthConfig struct {
+	Algorithm string `env:"JWT_ALGORITHM" yaml:"algorithm" json:"algorithm"`
+	Secret    string `env:"JWT_SECRET" yaml:"secret" json:"secret"`
+	JWKSFile  string `env:"JWT_JWKS_FILE" yaml:"jwks_file" json:"jwks_file"`
+	Issuer    string `env:"JWT_ISSUER" yaml:"issuer" json:"issuer"`
+	Audience  string `env:"JWT_AUDIENCE" yaml:"audience" json:"audience"`
+	// Disabled leaves the write routes open. Without it the service refuses
+	// to start when neither Secret nor JWKSFile is set.
+	Disabled bool `env:"AUTH_DISABLED" yaml:"disabled" json:"disabled"`
+}
+
+type KafkaConfig struct {
+	Brokers string `env:"KAFKA_BROKERS" yaml:"brokers" json:"brokers"`
+	GroupID string `env:"KAFKA_GROUP_ID" default:"product-service" yaml:"group_id" json:"group_id"`
+}
+
+type BrokerConfig struct {
+	// Driver is kafka (default), nats or memory.
+	Driver  string `env:"BROKER_DRIVER" yaml:"driver" json:"driver"`
+	NatsURL string `env:"NATS_URL" yaml:"nats_url" json:"nats_url"`
 }
 
 type AppConfig struct {
-	Port string   `yaml:"port" default:"8080" json:"port" env:"PORT"`
-	Db   DbConfig `yaml:"db" json:"db"`
+	Port   string       `yaml:"port" default:"8080" json:"port" env:"PORT"`
+	Db     DbConfig     `yaml:"db" json:"db"`
+	Auth   AuthConfig   `yaml:"auth" json:"auth"`
+	Kafka  KafkaConfig  `yaml:"kafka" json:"kafka"`
+	Broker BrokerConfig `yaml:"broker" json:"broker"`
+	// GrpcPort serves the product service over gRPC when set.
+	GrpcPort string `yaml:"grpc_port" json:"grpc_port" env:"GRPC_PORT"`
+	// OpenAPISpec validates the requests against this OpenAPI document when set,
+	// and the responses too when Env is development.
+	OpenAPISpec string `yaml:"openapi_spec" json:"openapi_spec" env:"OPENAPI_SPEC"`
+	Env         string `yaml:"env" json:"env" env:"APP_ENV"`
+	// CorsOrigins lists the browser origins allowed to call the API, default any.
+	CorsOrigins []string `yaml:"cors_origins" json:"cors_origins"`
+	// TrustedProxies lists the proxies whose X-Forwarded-For gives the client
+	// IP, default none.
+	TrustedProxies []string `yaml:"trusted_proxies" json:"trusted_proxies"`
 }
 
 func main() {
@@ -55,26 +113,158 @@ func main() {
 
 	app := ms.NewApplication(ms.Config{
 		AppConfig: ms.AppConfig{
-			Port:   cfg.Port,
-			Router: ms.Mux,
+			Port:           cfg.Port,
+			GRPC:           ms.GRPCConfig{Port: cfg.GrpcPort},
+			Router:         ms.Mux,
+			TrustedProxies: cfg.TrustedProxies,
+			// outbound calls are logged under this name
+			HTTPClient: ms.HTTPClientConfig{
+				AppName: "product-service",
+				Guard:   ms.GuardConfig{Breaker: &ms.BreakerConfig{}},
+			},
+			OpenAPI: ms.OpenAPIConfig{
+				Enabled: true,
+				Title:   "Product Service",
+				Version: "1.0.0",
+			},
+		},
+		KafkaConfig: ms.KafkaConfig{
+			Brokers: brokers(cfg.Kafka.Brokers),
+			GroupID: cfg.Kafka.GroupID,
 		},
+		NATSConfig: ms.NATSConfig{
+			URL:   cfg.Broker.NatsURL,
+			Group: cfg.Kafka.GroupID,
+		},
+		Broker:       ms.BrokerDriver(cfg.Broker.Driver),
+		PublishGuard: ms.GuardConfig{Breaker: &ms.BreakerConfig{}},
 	})
 
-	Router(app, client)
+	app.Use(
+		ms.CORS(ms.CORSConfig{AllowOrigins: cfg.CorsOrigins}),
+		ms.SecurityHeaders(ms.SecurityHeadersConfig{}),
+		ms.BodyLimit(1<<20),
+		ms.NewRateLimit(ms.RateLimitConfig{
+			RateLimit: ms.RateLimit{Rate: 100, Period: time.Minute},
+		}),
+	)
+
+	if cfg.OpenAPISpec != "" {
+		validator, err := ms.NewOpenAPIValidator(ms.OpenAPIValidatorConfig{
+			SpecFile:          cfg.OpenAPISpec,
+			ValidateResponses: cfg.Env == "development",
+		})
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		app.Use(validator)
+	}
+
+	var protected []ms.Middleware
+	switch {
+	case cfg.Auth.Disabled:
+		fmt.Println("warning: auth is disabled, POST /products and CreateProduct are open to anyone")
+	case cfg.Auth.Secret == "" && cfg.Auth.JWKSFile == "":
+		fmt.Println("auth: set auth.secret or auth.jwks_file, or auth.disabled to run without auth")
+		os.Exit(1)
+	default:
+		auth, err := ms.NewJWTMiddleware(ms.JWTConfig{
+			Algorithm: cfg.Auth.Algorithm,
+			Secret:    cfg.Auth.Secret,
+			JWKSFile:  cfg.Auth.JWKSFile,
+			Issuer:    cfg.Auth.Issuer,
+			Audience:  cfg.Auth.Audience,
+		})
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		protected = append(protected, auth, ms.RequireRole("admin"))
+	}
+
+	events := ms.NewEventBus(0)
+	Router(app, client, events, protected...)
+
+	if cfg.Kafka.Brokers != "" || cfg.Broker.Driver != "" {
+		// product events of the other instances
+		go func() {
+			if err := app.Consume(handler.ProductEventsTopic, productHandler(client, events).ForwardEvent); err != nil {
+				fmt.Println(err)
+			}
+		}()
+	}
 
 	app.Start()
 
 }
 
-func Router(app ms.IApplication, client *db.MongoClient) {
-	productDb := db.NewMongoDB(model.Product{}, client)
+func brokers(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
+
+// productGuard fails the product queries fast while Mongo is degraded, shared
+// by every product DataStore.
+var productGuard = ms.NewGuard("mongo.product", ms.GuardConfig{
+	Breaker:  &ms.BreakerConfig{FailureThreshold: 5, OpenTimeout: 30 * time.Second},
+	Bulkhead: &ms.BulkheadConfig{MaxConcurrent: 100, MaxWait: time.Second},
+})
+
+func productService(client *db.MongoClient) service.ProductService {
+	productDb := db.Guarded(db.NewMongoDB(model.Product{}, client), productGuard)
 	productRepository := repository.NewProductRepository(productDb)
-	productService := service.NewProductService(productRepository)
-	productHandler := handler.NewProductHandler(productService)
+	return service.NewProductService(productRepository)
+}
+
+func productHandler(client *db.MongoClient, events ms.EventBus) handler.ProductHandler {
+	return handler.NewProductHandler(productService(client), events)
+}
+
+// Router registers the product routes and gRPC service, protected guards the
+// write routes.
+func Router(app ms.IApplication, client *db.MongoClient, events ms.EventBus, protected ...ms.Middleware) {
+	productService := productService(client)
+	productHandler := handler.NewProductHandler(productService, events)
+
+	productpb.RegisterProductServiceServer(app, handler.NewProductGRPCServer(productService, events))
+	app.UseGRPC(ms.When(func(ctx ms.IContext) bool {
+		return ctx.Method() == productpb.ProductService_CreateProduct_FullMethodName
+	}, protected...))
+
+	app.Get("/health", ms.Health())
+	app.Get("/metrics", ms.Metrics())
+
+	// not on the stream, it lives as long as the client
+	timeout := ms.Timeout(10 * time.Second)
 
-	app.Get("/products/{id}", productHandler.GetProduct)
-	app.Get("/products", productHandler.GetProducts)
-	app.Post("/products", productHandler.CreateProduct)
+	app.Get("/products/stream", ms.Describe(ms.RouteDoc{
+		Summary:     "Stream product changes",
+		Description: "Server-Sent Events of the created and updated products.",
+		Tags:        []string{"products"},
+		Request:     handler.ProductStreamQuery{},
+	}, productHandler.StreamProducts))
+	app.WebSocket("/products/ws", productHandler.ProductSocket)
+	app.Get("/products/{id}", ms.Describe(ms.RouteDoc{
+		Summary:  "Get a product",
+		Tags:     []string{"products"},
+		Response: service.ResponseOne{},
+	}, productHandler.GetProduct), timeout)
+	app.Get("/products", ms.Describe(ms.RouteDoc{
+		Summary:  "List products",
+		Tags:     []string{"products"},
+		Request:  handler.ProductsQuery{},
+		Response: service.Response{},
+	}, productHandler.GetProducts), timeout)
+	app.Post("/products", ms.Describe(ms.RouteDoc{
+		Summary:  "Create a product",
+		Tags:     []string{"products"},
+		Request:  model.Product{},
+		Response: service.Response{},
+		Status:   http.StatusCreated,
+	}, productHandler.CreateProduct), append([]ms.Middleware{timeout}, protected...)...)
 }
 
 type User struct {
@@ -84,8 +274,17 @@ type User struct {
 	Email string `json:"email" bson:"email"`
 }
 
-func sql() {
-	tx := db.NewDB(User{})
+func sql(cfg DbConfig) error {
+	client, err := sqlClient(cfg)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Migrate(&User{}); err != nil {
+		return err
+	}
+	tx := db.NewGormDB[User](client)
 
 	// err := tx.Create(User{
 	// 	ID:    "3",
@@ -112,7 +311,7 @@ func sql() {
 	// fmt.Println(count, err)
 
 	// users, count, err := tx.FindAndCount(findOption)
-	result := tx.Find(findOption)
+	result := tx.Find(context.Background(), findOption)
 	if result.Err != nil {
 		fmt.Println(result.Err)
 	}
@@ -124,4 +323,5 @@ func sql() {
 	// fmt.Println(count)
 
 	fmt.Println("User created successfully")
+	return nil
 }
@@ -3,8 +3,8 @@ package model
 type Product struct {
 	ID          string  `json:"id" bson:"_id"`
 	Href        string  `json:"href,omitempty" bson:"-"`
-	Name        string  `json:"name,omitempty" bson:"name"`
+	Name        string  `json:"name,omitempty" bson:"name" validate:"required"`
 	Description string  `json:"description,omitempty" bson:"description,omitempty"`
-	Price       float64 `json:"price,omitempty" bson:"price,omitempty"`
-	Quantity    int     `json:"quantity,omitempty" bson:"quantity,omitempty"`
+	Price       float64 `json:"price,omitempty" bson:"price,omitempty" validate:"gt=0"`
+	Quantity    int     `json:"quantity,omitempty" bson:"quantity,omitempty" validate:"gte=0"`
 }
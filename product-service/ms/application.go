@@ -1,26 +1,73 @@
 package ms
 
 import (
-	"github.com/IBM/sarama"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
 )
 
 type IApplication interface {
 	Get(path string, handler HandleFunc, middlewares ...Middleware)
 	Post(path string, handler HandleFunc, middlewares ...Middleware)
+	Put(path string, handler HandleFunc, middlewares ...Middleware)
+	Patch(path string, handler HandleFunc, middlewares ...Middleware)
+	Delete(path string, handler HandleFunc, middlewares ...Middleware)
+	Options(path string, handler HandleFunc, middlewares ...Middleware)
+	// WebSocket upgrades the GET requests of path once middlewares accepted them.
+	WebSocket(path string, handler WebSocketHandler, middlewares ...Middleware)
 	Use(middlewares ...Middleware)
+	// RegisterEncoder adds or replaces the encoder of a content type,
+	// responses pick their encoder from the Accept header.
+	RegisterEncoder(encoder Encoder)
+	Routes() []Route
+	// Handler serves the HTTP routes, e.g. for httptest.
+	Handler() http.Handler
 	Start()
 
-	Consume(topic string, h ServiceHandleFunc) error
+	Consume(topic string, h ServiceHandleFunc, middlewares ...Middleware) error
+
+	// RegisterService exposes a gRPC service, e.g. with a generated
+	// RegisterXxxServer(app, srv). The gRPC server runs with Start when
+	// AppConfig.GRPC.Port is set.
+	RegisterService(desc *grpc.ServiceDesc, impl interface{})
+	// UseGRPC adds middlewares to the gRPC calls, see GRPCContext.
+	UseGRPC(middlewares ...Middleware)
 }
 
 type AppConfig struct {
-	Port   string
-	Router Router
+	Port        string
+	Router      Router
+	OpenAPI     OpenAPIConfig
+	Compression CompressionConfig
+	// StreamHeartbeat is how often Stream pings idle clients, default 15s.
+	StreamHeartbeat time.Duration
+	WebSocket       WebSocketConfig
+	GRPC            GRPCConfig
+	// HTTPClient configures the outbound calls of IContext.Call.
+	HTTPClient HTTPClientConfig
+	// TrustedProxies lists the proxies, IPs or CIDRs, whose X-Forwarded-For
+	// ClientIP reads on the gin router, none by default. The mux router
+	// never reads it.
+	TrustedProxies []string
+}
+
+// Route is an HTTP route registered on an IApplication.
+type Route struct {
+	Method  string
+	Path    string
+	Handler HandleFunc
 }
 
 type Config struct {
 	AppConfig   AppConfig
 	KafkaConfig KafkaConfig
+	NATSConfig  NATSConfig
+	// Broker carries SendMessage and Consume, Kafka by default.
+	Broker BrokerDriver
+	// PublishGuard puts SendMessage behind a circuit breaker and a bulkhead,
+	// named "broker:<driver>".
+	PublishGuard GuardConfig
 }
 
 // enum Router {gin, mux}
@@ -34,12 +81,8 @@ const (
 )
 
 type KafkaConfig struct {
-	Brokers     []string
-	GroupID     string
-	exitChannel chan bool
-
-	client   sarama.ConsumerGroup
-	producer *sarama.SyncProducer
+	Brokers []string
+	GroupID string
 }
 
 func NewApplication(cfg Config) IApplication {
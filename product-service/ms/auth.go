@@ -0,0 +1,388 @@
+package ms
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	claimsKey           = "ms.claims"
+	authorizationHeader = "Authorization"
+	// the headers of the claims forwarded with WithClaims
+	claimsSubjectHeader = "X-Claims-Subject"
+	claimsRolesHeader   = "X-Claims-Roles"
+	claimsScopesHeader  = "X-Claims-Scopes"
+)
+
+type JWTConfig struct {
+	// Algorithm is HS256 or RS256, default HS256.
+	Algorithm string
+	// Secret is the HS256 shared key.
+	Secret string
+	// PublicKey is an RS256 PEM encoded key, PublicKeyFile reads it from disk.
+	PublicKey     string
+	PublicKeyFile string
+	// JWKSFile is a local JSON Web Key Set, keys are picked by the token "kid".
+	JWKSFile string
+	Issuer   string
+	Audience string
+	Leeway   time.Duration
+	// RoleClaim is the claim holding roles, nested claims use dots,
+	// e.g. "realm_access.roles". Default "roles".
+	RoleClaim string
+	// ScopeClaim is the claim holding scopes, default "scope".
+	ScopeClaim string
+}
+
+// Claims is the identity of an authenticated request or message.
+type Claims struct {
+	Subject   string                 `json:"sub"`
+	Issuer    string                 `json:"iss,omitempty"`
+	Audience  []string               `json:"aud,omitempty"`
+	ExpiresAt time.Time              `json:"exp,omitempty"`
+	Roles     []string               `json:"roles,omitempty"`
+	Scopes    []string               `json:"scopes,omitempty"`
+	Raw       map[string]interface{} `json:"-"`
+	// Token is the bearer token the claims were read from.
+	Token string `json:"-"`
+}
+
+func (c *Claims) HasRole(role string) bool {
+	return c != nil && contains(c.Roles, role)
+}
+
+func (c *Claims) HasScope(scope string) bool {
+	return c != nil && contains(c.Scopes, scope)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// claimsFromContext reads the claims stored by the JWT middleware.
+func claimsFromContext(ctx IContext) *Claims {
+	claims, _ := ctx.Get(claimsKey).(*Claims)
+	return claims
+}
+
+type jwtVerifier struct {
+	cfg    JWTConfig
+	key    interface{}
+	keys   map[string]interface{}
+	parser *jwt.Parser
+}
+
+// NewJWTMiddleware returns a Middleware that requires a valid bearer token in the
+// Authorization header (or message header for consumers) and exposes its claims
+// through IContext.Claims.
+func NewJWTMiddleware(cfg JWTConfig) (Middleware, error) {
+	v, err := newJWTVerifier(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx IContext) error {
+			claims, err := v.verify(ctx.Header(authorizationHeader))
+			if err != nil {
+				return ctx.Response(http.StatusUnauthorized, ErrorResponse{
+					Status:  http.StatusUnauthorized,
+					Message: err.Error(),
+				})
+			}
+
+			ctx.Set(claimsKey, claims)
+			return next(ctx)
+		}
+	}, nil
+}
+
+// MessageClaims exposes the claims a producer forwarded with WithClaims
+// through IContext.Claims on consumers, for RequireRole and RequireScope.
+// The headers are taken as is, only trusted services may publish on the
+// broker. HTTP requests are left without claims, a client could set the
+// headers.
+//
+//	app.Consume("products", h, ms.MessageClaims(), ms.RequireRole("admin"))
+func MessageClaims() Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx IContext) error {
+			if _, ok := ctx.(*ConsumerContext); ok {
+				if subject := ctx.Header(claimsSubjectHeader); subject != "" {
+					ctx.Set(claimsKey, &Claims{
+						Subject: subject,
+						Roles:   splitHeader(ctx.Header(claimsRolesHeader)),
+						Scopes:  splitHeader(ctx.Header(claimsScopesHeader)),
+					})
+				}
+			}
+			return next(ctx)
+		}
+	}
+}
+
+func splitHeader(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// RequireRole allows the request when the caller has any of roles.
+func RequireRole(roles ...string) Middleware {
+	return requireClaims("role", func(claims *Claims) bool {
+		for _, role := range roles {
+			if claims.HasRole(role) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// RequireScope allows the request when the caller has all of scopes.
+func RequireScope(scopes ...string) Middleware {
+	return requireClaims("scope", func(claims *Claims) bool {
+		for _, scope := range scopes {
+			if !claims.HasScope(scope) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func requireClaims(kind string, allowed func(claims *Claims) bool) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx IContext) error {
+			claims := ctx.Claims()
+			if claims == nil {
+				return ctx.Response(http.StatusUnauthorized, ErrorResponse{
+					Status:  http.StatusUnauthorized,
+					Message: "missing credentials",
+				})
+			}
+
+			if !allowed(claims) {
+				return ctx.Response(http.StatusForbidden, ErrorResponse{
+					Status:  http.StatusForbidden,
+					Message: "insufficient " + kind,
+				})
+			}
+
+			return next(ctx)
+		}
+	}
+}
+
+func newJWTVerifier(cfg JWTConfig) (*jwtVerifier, error) {
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = jwt.SigningMethodHS256.Alg()
+	}
+	if cfg.RoleClaim == "" {
+		cfg.RoleClaim = "roles"
+	}
+	if cfg.ScopeClaim == "" {
+		cfg.ScopeClaim = "scope"
+	}
+
+	v := &jwtVerifier{cfg: cfg}
+
+	switch cfg.Algorithm {
+	case jwt.SigningMethodHS256.Alg():
+		if cfg.Secret != "" {
+			v.key = []byte(cfg.Secret)
+		}
+	case jwt.SigningMethodRS256.Alg():
+		pem := []byte(cfg.PublicKey)
+		if cfg.PublicKeyFile != "" {
+			file, err := os.ReadFile(cfg.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			pem = file
+		}
+		if len(pem) > 0 {
+			key, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			v.key = key
+		}
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm %s", cfg.Algorithm)
+	}
+
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = keys
+	}
+
+	if v.key == nil && len(v.keys) == 0 {
+		return nil, errors.New("jwt: no verification key configured")
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{cfg.Algorithm}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	v.parser = jwt.NewParser(opts...)
+
+	return v, nil
+}
+
+func (v *jwtVerifier) verify(header string) (*Claims, error) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, errors.New("missing bearer token")
+	}
+
+	mapClaims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(token, mapClaims, v.keyFunc); err != nil {
+		return nil, err
+	}
+
+	claims := &Claims{Raw: mapClaims, Token: token}
+	claims.Subject, _ = mapClaims.GetSubject()
+	claims.Issuer, _ = mapClaims.GetIssuer()
+	claims.Audience, _ = mapClaims.GetAudience()
+	if exp, _ := mapClaims.GetExpirationTime(); exp != nil {
+		claims.ExpiresAt = exp.Time
+	}
+	claims.Roles = claimStrings(lookupClaim(mapClaims, v.cfg.RoleClaim))
+	claims.Scopes = claimStrings(lookupClaim(mapClaims, v.cfg.ScopeClaim))
+	if len(claims.Scopes) == 0 {
+		claims.Scopes = claimStrings(mapClaims["scp"])
+	}
+
+	return claims, nil
+}
+
+func (v *jwtVerifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	if kid, ok := token.Header["kid"].(string); ok && v.keys != nil {
+		if key, ok := v.keys[kid]; ok {
+			return key, nil
+		}
+		if v.key == nil {
+			return nil, fmt.Errorf("unknown key id %s", kid)
+		}
+	}
+
+	if v.key == nil {
+		// a single key set without kid in the token
+		if len(v.keys) == 1 {
+			for _, key := range v.keys {
+				return key, nil
+			}
+		}
+		return nil, errors.New("token has no key id")
+	}
+
+	return v.key, nil
+}
+
+func lookupClaim(claims map[string]interface{}, path string) interface{} {
+	var current interface{} = claims
+	for _, part := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[part]
+	}
+	return current
+}
+
+// claimStrings reads a claim that is either a list or a space separated string.
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	case []string:
+		return v
+	}
+	return nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+func loadJWKS(path string) (map[string]interface{}, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(file, &set); err != nil {
+		return nil, fmt.Errorf("jwks %s: %w", path, err)
+	}
+
+	keys := map[string]interface{}{}
+	for _, k := range set.Keys {
+		switch k.Kty {
+		case "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(k.N)
+			if err != nil {
+				return nil, fmt.Errorf("jwks key %s: %w", k.Kid, err)
+			}
+			e, err := base64.RawURLEncoding.DecodeString(k.E)
+			if err != nil {
+				return nil, fmt.Errorf("jwks key %s: %w", k.Kid, err)
+			}
+			keys[k.Kid] = &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil {
+				return nil, fmt.Errorf("jwks key %s: %w", k.Kid, err)
+			}
+			keys[k.Kid] = secret
+		}
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,154 @@
+package ms
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func signHS256(t *testing.T, secret string, claims jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	assert.NoError(t, err)
+	return token
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	auth, err := NewJWTMiddleware(JWTConfig{Secret: "secret", Issuer: "product", Audience: "api"})
+	assert.NoError(t, err)
+
+	app := newMuxServer(Config{}).(*muxApplication)
+	app.Post("/products", func(ctx IContext) error {
+		return ctx.Response(http.StatusCreated, ctx.Claims().Subject)
+	}, auth, RequireRole("admin"), RequireScope("products:write"))
+
+	valid := jwt.MapClaims{
+		"sub":   "user-1",
+		"iss":   "product",
+		"aud":   "api",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"roles": []string{"admin"},
+		"scope": "products:read products:write",
+	}
+
+	cases := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{"Valid", "Bearer " + signHS256(t, "secret", valid), http.StatusCreated},
+		{"Missing", "", http.StatusUnauthorized},
+		{"WrongKey", "Bearer " + signHS256(t, "other", valid), http.StatusUnauthorized},
+		{"Expired", "Bearer " + signHS256(t, "secret", jwt.MapClaims{"sub": "user-1", "iss": "product", "aud": "api", "exp": time.Now().Add(-time.Minute).Unix()}), http.StatusUnauthorized},
+		{"WrongAudience", "Bearer " + signHS256(t, "secret", jwt.MapClaims{"sub": "user-1", "iss": "product", "aud": "web", "exp": time.Now().Add(time.Minute).Unix()}), http.StatusUnauthorized},
+		{"MissingRole", "Bearer " + signHS256(t, "secret", jwt.MapClaims{"sub": "user-1", "iss": "product", "aud": "api", "exp": time.Now().Add(time.Minute).Unix(), "roles": []string{"viewer"}, "scope": "products:write"}), http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/products", nil)
+			if tc.header != "" {
+				r.Header.Set("Authorization", tc.header)
+			}
+			w := httptest.NewRecorder()
+			app.mux.ServeHTTP(w, r)
+			assert.Equal(t, tc.status, w.Code)
+		})
+	}
+}
+
+func TestJWTMiddlewareJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	jwks, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, jwks, 0644))
+
+	auth, err := NewJWTMiddleware(JWTConfig{Algorithm: "RS256", JWKSFile: path, RoleClaim: "realm_access.roles"})
+	assert.NoError(t, err)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"sub":          "svc",
+		"exp":          time.Now().Add(time.Minute).Unix(),
+		"realm_access": map[string]interface{}{"roles": []string{"writer"}},
+	})
+	token.Header["kid"] = "k1"
+	signed, err := token.SignedString(key)
+	assert.NoError(t, err)
+
+	// consumers read the token from the message headers
+	ctx := NewConsumerContext(nil, &Message{
+		Headers: map[string]string{"authorization": "Bearer " + signed},
+	})
+
+	var claims *Claims
+	err = preHandle(func(ctx IContext) error {
+		claims = ctx.Claims()
+		return nil
+	}, auth, RequireRole("writer"))(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "svc", claims.Subject)
+}
+
+// recordBroker keeps the messages published on it.
+type recordBroker struct {
+	Broker
+	published []*Message
+}
+
+func (b *recordBroker) Publish(ctx context.Context, msg *Message) error {
+	b.published = append(b.published, msg)
+	return nil
+}
+
+func TestMessageClaims(t *testing.T) {
+	broker := &recordBroker{}
+	sender := NewConsumerContext(broker, &Message{})
+	sender.Set(claimsKey, &Claims{Subject: "u1", Roles: []string{"admin", "writer"}, Scopes: []string{"read"}, Token: "secret"})
+
+	// the identity is only sent when asked, never the token of the caller
+	assert.NoError(t, sender.SendMessage("products", "p1"))
+	assert.Empty(t, broker.published[0].Headers)
+	assert.NoError(t, sender.SendMessage("products", "p1", WithClaims(sender.Claims())))
+	headers := broker.published[1].Headers
+	assert.Equal(t, map[string]string{"X-Claims-Subject": "u1", "X-Claims-Roles": "admin,writer", "X-Claims-Scopes": "read"}, headers)
+	assert.NoError(t, sender.SendMessage("products", "p1", WithBearerToken("service")))
+	assert.Equal(t, "Bearer service", broker.published[2].Headers[authorizationHeader])
+
+	var claims *Claims
+	handler := preHandle(func(ctx IContext) error {
+		claims = ctx.Claims()
+		return nil
+	}, MessageClaims(), RequireRole("writer"))
+	assert.NoError(t, handler(NewConsumerContext(nil, &Message{Headers: headers})))
+	assert.Equal(t, &Claims{Subject: "u1", Roles: []string{"admin", "writer"}, Scopes: []string{"read"}}, claims)
+
+	// a client cannot set the claims of a request
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	for key, value := range headers {
+		r.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	handler(newMuxContext(w, r, nil, nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
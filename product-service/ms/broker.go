@@ -0,0 +1,234 @@
+package ms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var ErrBrokerNotSet = errors.New("message broker not set")
+
+// Message is a message of a Broker. Value is the json encoded payload.
+type Message struct {
+	Topic     string
+	Key       string
+	Value     []byte
+	Headers   map[string]string
+	Timestamp time.Time
+
+	// delivery is the driver's handle of a received message, for Ack and Nack.
+	delivery interface{}
+}
+
+// Broker carries the messages of SendMessage and Consume.
+type Broker interface {
+	Publish(ctx context.Context, msg *Message) error
+	// Subscribe delivers the messages of topic to handler until ctx is done.
+	// Each message goes to one subscriber of group, an empty group is the one
+	// of the driver config. Handlers Ack or Nack every message.
+	Subscribe(ctx context.Context, topic, group string, handler func(ctx context.Context, msg *Message)) error
+	Ack(ctx context.Context, msg *Message) error
+	// Nack hands msg back to the broker to be delivered again.
+	Nack(ctx context.Context, msg *Message) error
+	Close() error
+}
+
+type BrokerDriver string
+
+const (
+	KafkaBroker  BrokerDriver = "kafka"
+	NATSBroker   BrokerDriver = "nats"
+	MemoryBroker BrokerDriver = "memory"
+)
+
+// NewBroker opens the broker of cfg.Broker, Kafka by default.
+func NewBroker(cfg Config) (Broker, error) {
+	switch cfg.Broker {
+	case KafkaBroker, "":
+		return newKafkaBroker(cfg.KafkaConfig), nil
+	case NATSBroker:
+		return NewNATSBroker(cfg.NATSConfig)
+	case MemoryBroker:
+		return NewMemoryBroker(), nil
+	}
+	return nil, fmt.Errorf("unknown broker %q", cfg.Broker)
+}
+
+// lazyBroker opens the broker of an application on first use, so an
+// application that sends no message needs no broker running.
+type lazyBroker struct {
+	once   sync.Once
+	cfg    Config
+	broker Broker
+	err    error
+	guard  *Guard
+}
+
+func newLazyBroker(cfg Config) *lazyBroker {
+	b := &lazyBroker{cfg: cfg}
+	if cfg.PublishGuard.Breaker != nil || cfg.PublishGuard.Bulkhead != nil {
+		driver := cfg.Broker
+		if driver == "" {
+			driver = KafkaBroker
+		}
+		b.guard = NewGuard("broker:"+string(driver), cfg.PublishGuard)
+	}
+	return b
+}
+
+func (b *lazyBroker) open() (Broker, error) {
+	b.once.Do(func() {
+		b.broker, b.err = NewBroker(b.cfg)
+	})
+	return b.broker, b.err
+}
+
+func (b *lazyBroker) Publish(ctx context.Context, msg *Message) error {
+	broker, err := b.open()
+	if err != nil {
+		return err
+	}
+	return b.guard.Do(ctx, func() error {
+		return broker.Publish(ctx, msg)
+	})
+}
+
+func (b *lazyBroker) Subscribe(ctx context.Context, topic, group string, handler func(ctx context.Context, msg *Message)) error {
+	broker, err := b.open()
+	if err != nil {
+		return err
+	}
+	return broker.Subscribe(ctx, topic, group, handler)
+}
+
+func (b *lazyBroker) Ack(ctx context.Context, msg *Message) error {
+	broker, err := b.open()
+	if err != nil {
+		return err
+	}
+	return broker.Ack(ctx, msg)
+}
+
+func (b *lazyBroker) Nack(ctx context.Context, msg *Message) error {
+	broker, err := b.open()
+	if err != nil {
+		return err
+	}
+	return broker.Nack(ctx, msg)
+}
+
+func (b *lazyBroker) Close() error {
+	if b.broker == nil {
+		return nil
+	}
+	return b.broker.Close()
+}
+
+func newMessage(topic string, message interface{}, opts []OptionProducerMessage) (*Message, error) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &Message{Topic: topic, Value: data, Timestamp: time.Now()}
+	for _, opt := range opts {
+		if opt.key != "" {
+			msg.Key = opt.key
+		}
+		for _, header := range opt.headers {
+			for key, value := range header {
+				if msg.Headers == nil {
+					msg.Headers = map[string]string{}
+				}
+				msg.Headers[key] = value
+			}
+		}
+		if !opt.Timestamp.IsZero() {
+			msg.Timestamp = opt.Timestamp
+		}
+	}
+	return msg, nil
+}
+
+// WithHeader sets the header key of the message of SendMessage.
+func WithHeader(key, value string) OptionProducerMessage {
+	return OptionProducerMessage{headers: []map[string]string{{key: value}}}
+}
+
+// WithClaims forwards the subject, roles and scopes of claims, e.g. the
+// verified ctx.Claims(), in the headers of the message, read back by
+// MessageClaims. The bearer token is not forwarded, nil claims add nothing.
+//
+//	ctx.SendMessage("products", event, ms.WithClaims(ctx.Claims()))
+func WithClaims(claims *Claims) OptionProducerMessage {
+	if claims == nil {
+		return OptionProducerMessage{}
+	}
+	return OptionProducerMessage{headers: []map[string]string{{
+		claimsSubjectHeader: claims.Subject,
+		claimsRolesHeader:   strings.Join(claims.Roles, ","),
+		claimsScopesHeader:  strings.Join(claims.Scopes, ","),
+	}}}
+}
+
+// WithBearerToken sends token, e.g. a token of the service itself, in the
+// Authorization header of the message, for consumers checking it with the
+// JWT middleware.
+func WithBearerToken(token string) OptionProducerMessage {
+	return WithHeader(authorizationHeader, "Bearer "+token)
+}
+
+// sendMessage publishes message, the identity of ctx is only sent with
+// WithClaims or WithBearerToken.
+func sendMessage(ctx IContext, broker Broker, topic string, message interface{}, opts []OptionProducerMessage) error {
+	if broker == nil {
+		return ErrBrokerNotSet
+	}
+
+	msg, err := newMessage(topic, message, opts)
+	if err != nil {
+		return err
+	}
+	return broker.Publish(ctx.Context(), msg)
+}
+
+// consume runs h for the messages of topic until SIGINT or SIGTERM.
+func consume(broker Broker, client *HTTPClient, topic string, h ServiceHandleFunc, middlewares ...Middleware) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	return subscribe(ctx, broker, client, topic, h, middlewares...)
+}
+
+// subscribe runs h for the messages of topic until ctx is done. A message is
+// acked when h succeeds and nacked otherwise. The handlers call other
+// services with client.
+func subscribe(ctx context.Context, broker Broker, client *HTTPClient, topic string, h ServiceHandleFunc, middlewares ...Middleware) error {
+	if broker == nil {
+		return ErrBrokerNotSet
+	}
+
+	if len(middlewares) > 0 {
+		h = ServiceHandleFunc(preHandle(HandleFunc(h), middlewares...))
+	}
+
+	return broker.Subscribe(ctx, topic, "", func(ctx context.Context, msg *Message) {
+		if err := h(newConsumerContext(ctx, broker, client, msg)); err != nil {
+			log.Printf("error: %v", err)
+			if err := broker.Nack(ctx, msg); err != nil {
+				log.Printf("nack: %v", err)
+			}
+			return
+		}
+		if err := broker.Ack(ctx, msg); err != nil {
+			log.Printf("ack: %v", err)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package ms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/IBM/sarama"
+)
+
+type kafkaBroker struct {
+	cfg      KafkaConfig
+	mu       sync.Mutex
+	producer sarama.SyncProducer
+}
+
+func newKafkaBroker(cfg KafkaConfig) *kafkaBroker {
+	return &kafkaBroker{cfg: cfg}
+}
+
+func (b *kafkaBroker) Publish(ctx context.Context, msg *Message) error {
+	b.mu.Lock()
+	if b.producer == nil {
+		if len(b.cfg.Brokers) == 0 {
+			b.mu.Unlock()
+			return fmt.Errorf("kafka brokers not set")
+		}
+
+		p, err := newProducer(b.cfg.Brokers)
+		if err != nil {
+			b.mu.Unlock()
+			return err
+		}
+		b.producer = p
+	}
+	p := b.producer
+	b.mu.Unlock()
+
+	return producer(p, msg)
+}
+
+// Subscribe consumes topic with a consumer group, KafkaConfig.GroupID by
+// default. SIGUSR1 pauses and resumes the consumption.
+func (b *kafkaBroker) Subscribe(ctx context.Context, topic, group string, handler func(ctx context.Context, msg *Message)) error {
+	if len(b.cfg.Brokers) == 0 {
+		return fmt.Errorf("kafka brokers not set")
+	}
+
+	if group == "" {
+		group = b.cfg.GroupID
+	}
+	if group == "" {
+		return fmt.Errorf("kafka group id not set")
+	}
+
+	client, err := newConsumer(b.cfg.Brokers, group)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	groupHandler := &ConsumerGroupHandler{handler: handler}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			if err := client.Consume(ctx, []string{topic}, groupHandler); err != nil {
+				log.Printf("Error from consumer: %v", err)
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+
+	consumptionIsPaused := false
+	sigusr1 := make(chan os.Signal, 1)
+	signal.Notify(sigusr1, syscall.SIGUSR1)
+	defer signal.Stop(sigusr1)
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("terminating: context cancelled")
+			wg.Wait()
+			return nil
+		case <-sigusr1:
+			toggleConsumptionFlow(client, &consumptionIsPaused)
+		}
+	}
+}
+
+func (b *kafkaBroker) Ack(ctx context.Context, msg *Message) error {
+	delivery, ok := msg.delivery.(*kafkaDelivery)
+	if !ok {
+		return errors.New("not a kafka message")
+	}
+	delivery.session.MarkMessage(delivery.msg, "")
+	return nil
+}
+
+func (b *kafkaBroker) Nack(ctx context.Context, msg *Message) error {
+	delivery, ok := msg.delivery.(*kafkaDelivery)
+	if !ok {
+		return errors.New("not a kafka message")
+	}
+	delivery.nacked = true
+	return nil
+}
+
+func (b *kafkaBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.producer == nil {
+		return nil
+	}
+	err := b.producer.Close()
+	b.producer = nil
+	return err
+}
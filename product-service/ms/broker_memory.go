@@ -0,0 +1,115 @@
+package ms
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+)
+
+const (
+	memoryQueueSize     = 256
+	memoryMaxDeliveries = 5
+)
+
+type memoryDelivery struct {
+	queue      chan *Message
+	deliveries int
+}
+
+// memoryBroker keeps a queue per topic and group. Messages published before
+// a group subscribed are not delivered to it.
+type memoryBroker struct {
+	mu     sync.Mutex
+	queues map[string]map[string]chan *Message
+	closed bool
+}
+
+// NewMemoryBroker returns a Broker that delivers messages within the process,
+// for tests and local development. A nacked message is delivered again up to
+// 5 times.
+func NewMemoryBroker() Broker {
+	return &memoryBroker{queues: map[string]map[string]chan *Message{}}
+}
+
+func (b *memoryBroker) queue(topic, group string) chan *Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.queues[topic] == nil {
+		b.queues[topic] = map[string]chan *Message{}
+	}
+	q, ok := b.queues[topic][group]
+	if !ok {
+		q = make(chan *Message, memoryQueueSize)
+		b.queues[topic][group] = q
+	}
+	return q
+}
+
+func (b *memoryBroker) Publish(ctx context.Context, msg *Message) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return errors.New("broker closed")
+	}
+	queues := make([]chan *Message, 0, len(b.queues[msg.Topic]))
+	for _, q := range b.queues[msg.Topic] {
+		queues = append(queues, q)
+	}
+	b.mu.Unlock()
+
+	for _, q := range queues {
+		m := *msg
+		m.delivery = &memoryDelivery{queue: q}
+		select {
+		case q <- &m:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (b *memoryBroker) Subscribe(ctx context.Context, topic, group string, handler func(ctx context.Context, msg *Message)) error {
+	q := b.queue(topic, group)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-q:
+			handler(ctx, msg)
+		}
+	}
+}
+
+func (b *memoryBroker) Ack(ctx context.Context, msg *Message) error {
+	return nil
+}
+
+func (b *memoryBroker) Nack(ctx context.Context, msg *Message) error {
+	delivery, ok := msg.delivery.(*memoryDelivery)
+	if !ok {
+		return errors.New("not a memory message")
+	}
+
+	delivery.deliveries++
+	if delivery.deliveries >= memoryMaxDeliveries {
+		log.Printf("memory broker: dropping message of %s after %d deliveries", msg.Topic, delivery.deliveries)
+		return nil
+	}
+
+	select {
+	case delivery.queue <- msg:
+		return nil
+	default:
+		return errors.New("memory broker: queue full")
+	}
+}
+
+func (b *memoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
@@ -0,0 +1,159 @@
+package ms
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+type NATSConfig struct {
+	URL string
+	// Stream is the JetStream stream of the topics, default "ms". A topic is
+	// published on the subject "<stream>.<topic>".
+	Stream string
+	// Group names the durable consumers of Consume, one per topic, empty for
+	// ephemeral ones.
+	Group string
+}
+
+type natsBroker struct {
+	cfg  NATSConfig
+	conn *nats.Conn
+	js   jetstream.JetStream
+}
+
+// NewNATSBroker connects to NATS and creates the JetStream stream of cfg when missing.
+func NewNATSBroker(cfg NATSConfig) (Broker, error) {
+	if cfg.URL == "" {
+		cfg.URL = nats.DefaultURL
+	}
+	if cfg.Stream == "" {
+		cfg.Stream = "ms"
+	}
+
+	conn, err := nats.Connect(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     cfg.Stream,
+		Subjects: []string{cfg.Stream + ".>"},
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &natsBroker{cfg: cfg, conn: conn, js: js}, nil
+}
+
+func (b *natsBroker) subject(topic string) string {
+	return b.cfg.Stream + "." + topic
+}
+
+func (b *natsBroker) Publish(ctx context.Context, msg *Message) error {
+	m := nats.NewMsg(b.subject(msg.Topic))
+	m.Data = msg.Value
+	for key, value := range msg.Headers {
+		m.Header.Set(key, value)
+	}
+	if msg.Key != "" {
+		m.Header.Set("Ms-Key", msg.Key)
+	}
+
+	_, err := b.js.PublishMsg(ctx, m)
+	return err
+}
+
+func (b *natsBroker) Subscribe(ctx context.Context, topic, group string, handler func(ctx context.Context, msg *Message)) error {
+	if group == "" {
+		group = b.cfg.Group
+	}
+
+	consumer, err := b.js.CreateOrUpdateConsumer(ctx, b.cfg.Stream, jetstream.ConsumerConfig{
+		Durable:       durableName(group, topic),
+		FilterSubject: b.subject(topic),
+		AckPolicy:     jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		return err
+	}
+
+	cc, err := consumer.Consume(func(m jetstream.Msg) {
+		handler(ctx, natsMessage(topic, m))
+	})
+	if err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+	cc.Stop()
+	return nil
+}
+
+// durableReplacer replaces the characters durable names may not contain.
+var durableReplacer = strings.NewReplacer(".", "_", "*", "_", ">", "_", "/", "_", "\\", "_", " ", "_", "\t", "_")
+
+// durableName is the durable consumer of group on topic, one per topic so
+// the subscriptions of a group to several topics do not replace each other's
+// filter. It is empty, an ephemeral consumer, without a group.
+func durableName(group, topic string) string {
+	if group == "" {
+		return ""
+	}
+	return durableReplacer.Replace(group + "_" + topic)
+}
+
+func natsMessage(topic string, m jetstream.Msg) *Message {
+	msg := &Message{
+		Topic:    topic,
+		Value:    m.Data(),
+		Headers:  map[string]string{},
+		delivery: m,
+	}
+	for key := range m.Headers() {
+		if key == "Ms-Key" {
+			msg.Key = m.Headers().Get(key)
+			continue
+		}
+		msg.Headers[key] = m.Headers().Get(key)
+	}
+	if meta, err := m.Metadata(); err == nil {
+		msg.Timestamp = meta.Timestamp
+	}
+	return msg
+}
+
+func (b *natsBroker) Ack(ctx context.Context, msg *Message) error {
+	m, ok := msg.delivery.(jetstream.Msg)
+	if !ok {
+		return errors.New("not a nats message")
+	}
+	return m.Ack()
+}
+
+func (b *natsBroker) Nack(ctx context.Context, msg *Message) error {
+	m, ok := msg.delivery.(jetstream.Msg)
+	if !ok {
+		return errors.New("not a nats message")
+	}
+	return m.Nak()
+}
+
+func (b *natsBroker) Close() error {
+	return b.conn.Drain()
+}
@@ -0,0 +1,80 @@
+package ms
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBroker(t *testing.T) {
+	broker := NewMemoryBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type event struct {
+		Name string `json:"name"`
+	}
+
+	received := make(chan string, 4)
+	attempts := 0
+	go func() {
+		subscribe(ctx, broker, nil, "products", func(ctx IContext) error {
+			attempts++
+			if attempts == 1 {
+				// nacked, delivered again
+				return errors.New("not yet")
+			}
+
+			var e event
+			if err := ctx.ReadInput(&e); err != nil {
+				return err
+			}
+			received <- e.Name + " " + ctx.Header("X-Trace")
+			return nil
+		})
+	}()
+	// the group queue exists once Subscribe ran
+	assert.Eventually(t, func() bool {
+		b := broker.(*memoryBroker)
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return len(b.queues["products"]) == 1
+	}, time.Second, time.Millisecond)
+
+	sender := newMuxContext(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil), broker, nil)
+	err := sender.SendMessage("products", event{Name: "p1"}, OptionProducerMessage{
+		headers: []map[string]string{{"X-Trace": "abc"}},
+	})
+	assert.NoError(t, err)
+
+	select {
+	case got := <-received:
+		assert.Equal(t, "p1 abc", got)
+		assert.Equal(t, 2, attempts)
+	case <-time.After(time.Second):
+		t.Fatal("message not delivered")
+	}
+}
+
+func TestNewBroker(t *testing.T) {
+	broker, err := NewBroker(Config{Broker: MemoryBroker})
+	assert.NoError(t, err)
+	assert.IsType(t, &memoryBroker{}, broker)
+
+	_, err = NewBroker(Config{Broker: "rabbit"})
+	assert.Error(t, err)
+
+	ctx := newMuxContext(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil), nil, nil)
+	assert.ErrorIs(t, ctx.SendMessage("products", "p1"), ErrBrokerNotSet)
+}
+
+func TestDurableName(t *testing.T) {
+	assert.Equal(t, "product-service_product_events", durableName("product-service", "product.events"))
+	assert.Equal(t, "a_b_c_d_e", durableName("a*b", "c>d e"))
+	assert.NotEqual(t, durableName("g", "orders"), durableName("g", "products"))
+	assert.Empty(t, durableName("", "products"))
+}
@@ -4,18 +4,21 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 
 	"github.com/IBM/sarama"
 )
 
+// ConsumerGroupHandler hands the messages of a Kafka claim to a Broker handler.
 type ConsumerGroupHandler struct {
-	cfg   KafkaConfig
-	h     ServiceHandleFunc
-	topic string
+	handler func(ctx context.Context, msg *Message)
+}
+
+// kafkaDelivery marks acked messages on the session, a nacked message ends
+// the claim so it is consumed again from the last marked offset.
+type kafkaDelivery struct {
+	session sarama.ConsumerGroupSession
+	msg     *sarama.ConsumerMessage
+	nacked  bool
 }
 
 func (handler *ConsumerGroupHandler) Setup(sarama.ConsumerGroupSession) error {
@@ -27,14 +30,11 @@ func (handler *ConsumerGroupHandler) Cleanup(sarama.ConsumerGroupSession) error
 
 func (handler *ConsumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	for msg := range claim.Messages() {
-		// set context
-		ctx := NewConsumerContext(&handler.cfg, msg)
-		if err := handler.h(ctx); err != nil {
-			// log error
-			log.Printf("error: %v", err)
-			return err
+		delivery := &kafkaDelivery{session: session, msg: msg}
+		handler.handler(session.Context(), kafkaMessage(msg, delivery))
+		if delivery.nacked {
+			return fmt.Errorf("message nacked: %s/%d/%d", msg.Topic, msg.Partition, msg.Offset)
 		}
-		session.MarkMessage(msg, "")
 	}
 	return nil
 }
@@ -67,68 +67,19 @@ func toggleConsumptionFlow(client sarama.ConsumerGroup, isPaused *bool) {
 	*isPaused = !*isPaused
 }
 
-func consume(kafkaConfig *KafkaConfig, topic string, h ServiceHandleFunc) error {
-	if kafkaConfig.client == nil {
-		if kafkaConfig.Brokers == nil {
-			return fmt.Errorf("kafka brokers not set")
+func kafkaMessage(msg *sarama.ConsumerMessage, delivery *kafkaDelivery) *Message {
+	headers := map[string]string{}
+	for _, header := range msg.Headers {
+		if header != nil {
+			headers[string(header.Key)] = string(header.Value)
 		}
-
-		if kafkaConfig.GroupID == "" {
-			return fmt.Errorf("kafka group id not set")
-		}
-
-		client, err := newConsumer(kafkaConfig.Brokers, kafkaConfig.GroupID)
-		if err != nil {
-			return err
-		}
-
-		kafkaConfig.client = client
-	}
-
-	defer kafkaConfig.client.Close()
-
-	handler := &ConsumerGroupHandler{
-		h:     h,
-		topic: topic,
-		cfg:   *kafkaConfig,
 	}
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	go func() {
-		defer wg.Done()
-		for {
-			if err := kafkaConfig.client.Consume(ctx, []string{topic}, handler); err != nil {
-				log.Printf("Error from consumer: %v", err)
-			}
-
-			if ctx.Err() != nil {
-				return
-			}
-		}
-	}()
-
-	// Handle graceful shutdown
-	consumptionIsPaused := false
-	sigusr1 := make(chan os.Signal, 1)
-	signal.Notify(sigusr1, syscall.SIGUSR1)
-
-	sigterm := make(chan os.Signal, 1)
-	signal.Notify(sigterm, syscall.SIGINT, syscall.SIGTERM)
-	select {
-	case <-sigterm:
-		fmt.Println("Received termination signal. Initiating shutdown...")
-		cancel()
-	case <-ctx.Done():
-		fmt.Println("terminating: context cancelled")
-	case <-sigusr1:
-		toggleConsumptionFlow(kafkaConfig.client, &consumptionIsPaused)
+	return &Message{
+		Topic:     msg.Topic,
+		Key:       string(msg.Key),
+		Value:     msg.Value,
+		Headers:   headers,
+		Timestamp: msg.Timestamp,
+		delivery:  delivery,
 	}
-	// Wait for the consumer to finish processing
-	wg.Wait()
-	return nil
 }
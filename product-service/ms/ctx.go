@@ -1,15 +1,35 @@
 package ms
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type IContext interface {
+	// Context is cancelled when the client goes away or the consumer session ends.
+	Context() context.Context
+	SetContext(ctx context.Context)
 	Log(message string)
 	Param(name string) string
 	Query(name string) string
+	Method() string
+	Header(name string) string
+	SetHeader(name, value string)
+	ClientIP() string
+	Set(key string, value interface{})
+	Get(key string) interface{}
+	Claims() *Claims
 	ReadInput(data interface{}) error
 	Response(responseCode int, responseData interface{}) error
+	// Stream answers with Server-Sent Events written by fn until it returns.
+	Stream(fn func(w SSEWriter) error) error
 
 	SendMessage(topic string, message interface{}, opts ...OptionProducerMessage) error
+	// Call sends an outbound HTTP request with the HTTPClient of the
+	// application, see HTTPClient.Do.
+	Call(req HTTPRequest) (*HTTPResponse, error)
+	// CallAll sends reqs concurrently, see HTTPClient.DoAll.
+	CallAll(reqs ...HTTPRequest) ([]*HTTPResponse, error)
 }
 type HandleFunc func(ctx IContext) error
 
@@ -21,6 +41,8 @@ type OptionProducerMessage struct {
 	key       string
 	headers   []map[string]string
 	Timestamp time.Time
+	// Metadata, Offset and Partition are ignored since messages go through a
+	// Broker, Kafka picks the partition from the key.
 	Metadata  interface{}
 	Offset    int64
 	Partition int32
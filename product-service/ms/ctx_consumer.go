@@ -1,40 +1,56 @@
 package ms
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"reflect"
-
-	"github.com/IBM/sarama"
+	"strings"
 )
 
 type ConsumerContext struct {
+	ctx     context.Context
 	message string
-	msg     *sarama.ConsumerMessage
-	cfg     *KafkaConfig
+	msg     *Message
+	broker  Broker
+	client  *HTTPClient
+	values  map[string]interface{}
+}
+
+func NewConsumerContext(broker Broker, msg *Message) IContext {
+	return newConsumerContext(context.Background(), broker, nil, msg)
 }
 
-func NewConsumerContext(cfg *KafkaConfig, msg *sarama.ConsumerMessage) IContext {
+func newConsumerContext(ctx context.Context, broker Broker, client *HTTPClient, msg *Message) IContext {
 	return &ConsumerContext{
+		ctx:     ctx,
 		message: string(msg.Value),
 		msg:     msg,
-		cfg:     cfg,
+		broker:  broker,
+		client:  client,
 	}
 }
 
-func (c *ConsumerContext) SendMessage(topic string, message interface{}, opts ...OptionProducerMessage) error {
-	p := newProducer(c.cfg.Brokers)
-	c.cfg.producer = &p
+func (c *ConsumerContext) Context() context.Context {
+	return c.ctx
+}
 
-	err := producer(*c.cfg.producer, topic, message, opts...)
-	if err != nil {
-		return err
-	}
+func (c *ConsumerContext) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
 
-	defer p.Close()
+func (c *ConsumerContext) SendMessage(topic string, message interface{}, opts ...OptionProducerMessage) error {
+	return sendMessage(c, c.broker, topic, message, opts)
+}
 
-	return nil
+func (c *ConsumerContext) Call(req HTTPRequest) (*HTTPResponse, error) {
+	return httpClientOf(c.client).Do(c, req)
+}
+
+func (c *ConsumerContext) CallAll(reqs ...HTTPRequest) ([]*HTTPResponse, error) {
+	return httpClientOf(c.client).DoAll(c, reqs...)
 }
 
 func (c *ConsumerContext) Log(message string) {
@@ -49,6 +65,45 @@ func (c *ConsumerContext) Param(name string) string {
 	return ""
 }
 
+func (c *ConsumerContext) Method() string {
+	return ""
+}
+
+// Header reads a message header, e.g. one set with WithHeader or WithClaims.
+func (c *ConsumerContext) Header(name string) string {
+	for key, value := range c.msg.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+func (c *ConsumerContext) SetHeader(name, value string) {}
+
+func (c *ConsumerContext) Stream(fn func(w SSEWriter) error) error {
+	return ErrStreamUnsupported
+}
+
+func (c *ConsumerContext) ClientIP() string {
+	return ""
+}
+
+func (c *ConsumerContext) Set(key string, value interface{}) {
+	if c.values == nil {
+		c.values = map[string]interface{}{}
+	}
+	c.values[key] = value
+}
+
+func (c *ConsumerContext) Get(key string) interface{} {
+	return c.values[key]
+}
+
+func (c *ConsumerContext) Claims() *Claims {
+	return claimsFromContext(c)
+}
+
 func (ctx *ConsumerContext) ReadInput(data interface{}) error {
 	const errMsgFormat = "%s, payload: %s"
 	val := reflect.ValueOf(&data)
@@ -62,7 +117,7 @@ func (ctx *ConsumerContext) ReadInput(data interface{}) error {
 		if err := json.Unmarshal([]byte(ctx.message), data); err != nil {
 			return fmt.Errorf(errMsgFormat, err.Error(), ctx.message)
 		}
-		return nil
+		return validateInput(data)
 	default:
 		err := json.Unmarshal([]byte(ctx.message), &data)
 		if err != nil {
@@ -72,6 +127,14 @@ func (ctx *ConsumerContext) ReadInput(data interface{}) error {
 	}
 }
 
+// ReadBody decodes the message like ReadInput without validating it.
+func (ctx *ConsumerContext) ReadBody(data interface{}) error {
+	if ctx.message == "" {
+		return io.EOF
+	}
+	return json.Unmarshal([]byte(ctx.message), data)
+}
+
 func (c *ConsumerContext) Response(responseCode int, responseData interface{}) error {
 	fmt.Println("Response:", responseData)
 	return nil
@@ -1,32 +1,41 @@
 package ms
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
 type GinContext struct {
-	ctx *gin.Context
-	cfg *KafkaConfig
+	ctx    *gin.Context
+	broker Broker
+	out    *output
 }
 
-func newGinContext(c *gin.Context, cfg *KafkaConfig) IContext {
-	return &GinContext{ctx: c, cfg: cfg}
+func newGinContext(c *gin.Context, broker Broker, out *output) IContext {
+	return &GinContext{ctx: c, broker: broker, out: out}
 }
 
 func (c *GinContext) SendMessage(topic string, message interface{}, opts ...OptionProducerMessage) error {
-	p := newProducer(c.cfg.Brokers)
-	c.cfg.producer = &p
+	return sendMessage(c, c.broker, topic, message, opts)
+}
 
-	err := producer(*c.cfg.producer, topic, message, opts...)
-	if err != nil {
-		return err
-	}
+func (c *GinContext) Call(req HTTPRequest) (*HTTPResponse, error) {
+	return c.out.httpClient().Do(c, req)
+}
 
-	defer p.Close()
+func (c *GinContext) CallAll(reqs ...HTTPRequest) ([]*HTTPResponse, error) {
+	return c.out.httpClient().DoAll(c, reqs...)
+}
+
+func (c *GinContext) Context() context.Context {
+	return c.ctx.Request.Context()
+}
 
-	return nil
+func (c *GinContext) SetContext(ctx context.Context) {
+	c.ctx.Request = c.ctx.Request.WithContext(ctx)
 }
 
 func (c *GinContext) Log(message string) {
@@ -34,7 +43,36 @@ func (c *GinContext) Log(message string) {
 }
 
 func (c *GinContext) Query(name string) string {
-	return c.ctx.Query(name)
+	return queryParam(c.ctx.Request.URL.RawQuery, name)
+}
+
+func (c *GinContext) Method() string {
+	return c.ctx.Request.Method
+}
+
+func (c *GinContext) Header(name string) string {
+	return c.ctx.GetHeader(name)
+}
+
+func (c *GinContext) SetHeader(name, value string) {
+	c.ctx.Header(name, value)
+}
+
+func (c *GinContext) ClientIP() string {
+	return c.ctx.ClientIP()
+}
+
+func (c *GinContext) Set(key string, value interface{}) {
+	c.ctx.Set(key, value)
+}
+
+func (c *GinContext) Get(key string) interface{} {
+	value, _ := c.ctx.Get(key)
+	return value
+}
+
+func (c *GinContext) Claims() *Claims {
+	return claimsFromContext(c)
 }
 
 func (c *GinContext) Param(name string) string {
@@ -42,10 +80,26 @@ func (c *GinContext) Param(name string) string {
 }
 
 func (c *GinContext) ReadInput(data interface{}) error {
-	return c.ctx.BindJSON(data)
+	if err := c.ReadBody(data); err != nil {
+		return err
+	}
+	return validateInput(data)
+}
+
+// ReadBody decodes the body like ReadInput without validating it.
+func (c *GinContext) ReadBody(data interface{}) error {
+	limitBody(c, c.ctx.Writer, c.ctx.Request)
+	return bodyError(c.ctx.ShouldBindJSON(data))
 }
 
 func (c *GinContext) Response(responseCode int, responseData interface{}) error {
-	c.ctx.JSON(responseCode, responseData)
-	return nil
+	return writeResponse(c.ctx.Writer, c.ctx.Request, c.out, responseCode, responseData)
+}
+
+func (c *GinContext) httpRequest() (http.ResponseWriter, *http.Request) {
+	return c.ctx.Writer, c.ctx.Request
+}
+
+func (c *GinContext) Stream(fn func(w SSEWriter) error) error {
+	return stream(c.ctx.Writer, c.ctx.Request, c.out.streamHeartbeat(), fn)
 }
@@ -4,25 +4,45 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 )
 
 type HttpContext struct {
-	w   http.ResponseWriter
-	r   *http.Request
-	cfg *KafkaConfig
+	w      http.ResponseWriter
+	r      *http.Request
+	broker Broker
+	out    *output
+	values map[string]interface{}
 }
 
-func newMuxContext(w http.ResponseWriter, r *http.Request, cfg *KafkaConfig) IContext {
+func newMuxContext(w http.ResponseWriter, r *http.Request, broker Broker, out *output) IContext {
 	return &HttpContext{
-		w:   w,
-		r:   r,
-		cfg: cfg,
+		w:      w,
+		r:      r,
+		broker: broker,
+		out:    out,
 	}
 }
 
 func (c *HttpContext) SendMessage(topic string, message interface{}, opts ...OptionProducerMessage) error {
-	return nil
+	return sendMessage(c, c.broker, topic, message, opts)
+}
+
+func (c *HttpContext) Call(req HTTPRequest) (*HTTPResponse, error) {
+	return c.out.httpClient().Do(c, req)
+}
+
+func (c *HttpContext) CallAll(reqs ...HTTPRequest) ([]*HTTPResponse, error) {
+	return c.out.httpClient().DoAll(c, reqs...)
+}
+
+func (c *HttpContext) Context() context.Context {
+	return c.r.Context()
+}
+
+func (c *HttpContext) SetContext(ctx context.Context) {
+	c.r = c.r.WithContext(ctx)
 }
 
 func (c *HttpContext) Log(message string) {
@@ -30,7 +50,43 @@ func (c *HttpContext) Log(message string) {
 }
 
 func (c *HttpContext) Query(name string) string {
-	return c.r.URL.Query().Get(name)
+	return queryParam(c.r.URL.RawQuery, name)
+}
+
+func (c *HttpContext) Method() string {
+	return c.r.Method
+}
+
+func (c *HttpContext) Header(name string) string {
+	return c.r.Header.Get(name)
+}
+
+func (c *HttpContext) SetHeader(name, value string) {
+	c.w.Header().Set(name, value)
+}
+
+// ClientIP is the remote address of the connection, forwarded headers are not trusted.
+func (c *HttpContext) ClientIP() string {
+	host, _, err := net.SplitHostPort(c.r.RemoteAddr)
+	if err != nil {
+		return c.r.RemoteAddr
+	}
+	return host
+}
+
+func (c *HttpContext) Set(key string, value interface{}) {
+	if c.values == nil {
+		c.values = map[string]interface{}{}
+	}
+	c.values[key] = value
+}
+
+func (c *HttpContext) Get(key string) interface{} {
+	return c.values[key]
+}
+
+func (c *HttpContext) Claims() *Claims {
+	return claimsFromContext(c)
 }
 
 func (c *HttpContext) Param(name string) string {
@@ -40,18 +96,30 @@ func (c *HttpContext) Param(name string) string {
 	case string:
 		value = v
 	}
-	c.r = c.r.WithContext(context.WithValue(c.r.Context(), ContextKey(name), nil))
 	return value
 }
 
 func (c *HttpContext) ReadInput(data interface{}) error {
-	return json.NewDecoder(c.r.Body).Decode(data)
+	if err := c.ReadBody(data); err != nil {
+		return err
+	}
+	return validateInput(data)
+}
+
+// ReadBody decodes the body like ReadInput without validating it.
+func (c *HttpContext) ReadBody(data interface{}) error {
+	limitBody(c, c.w, c.r)
+	return bodyError(json.NewDecoder(c.r.Body).Decode(data))
 }
 
 func (c *HttpContext) Response(responseCode int, responseData interface{}) error {
-	c.w.Header().Set("Content-type", "application/json; charset=UTF8")
+	return writeResponse(c.w, c.r, c.out, responseCode, responseData)
+}
 
-	c.w.WriteHeader(responseCode)
+func (c *HttpContext) httpRequest() (http.ResponseWriter, *http.Request) {
+	return c.w, c.r
+}
 
-	return json.NewEncoder(c.w).Encode(responseData)
+func (c *HttpContext) Stream(fn func(w SSEWriter) error) error {
+	return stream(c.w, c.r, c.out.streamHeartbeat(), fn)
 }
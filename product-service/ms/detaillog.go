@@ -0,0 +1,78 @@
+package ms
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// DetailLog is a line of the detail log, the format the node services write
+// for every call they make or receive.
+type DetailLog struct {
+	LogType         string        `json:"LogType"`
+	Host            string        `json:"Host"`
+	AppName         string        `json:"AppName"`
+	Instance        int           `json:"Instance"`
+	Session         string        `json:"Session"`
+	InitInvoke      string        `json:"InitInvoke"`
+	Scenario        string        `json:"Scenario"`
+	InputTimeStamp  string        `json:"InputTimeStamp"`
+	OutputTimeStamp string        `json:"OutputTimeStamp,omitempty"`
+	Custom          *DetailCustom `json:"Custom,omitempty"`
+	ProcessingTime  string        `json:"ProcessingTime,omitempty"`
+}
+
+type DetailCustom struct {
+	Invoke   string      `json:"Invoke"`
+	Event    string      `json:"Event"`
+	Protocol string      `json:"Protocol,omitempty"`
+	Type     string      `json:"Type"`
+	RawData  string      `json:"RawData,omitempty"`
+	Data     interface{} `json:"Data,omitempty"`
+	ResTime  string      `json:"ResTime,omitempty"`
+}
+
+// detailLogger writes DetailLog lines, one json object per line.
+type detailLogger struct {
+	mu      sync.Mutex
+	w       io.Writer
+	appName string
+	host    string
+}
+
+func newDetailLogger(w io.Writer, appName string) *detailLogger {
+	if w == nil {
+		w = os.Stdout
+	}
+	host, _ := os.Hostname()
+	return &detailLogger{w: w, appName: appName, host: host}
+}
+
+// write logs custom for the call of session started at start.
+func (l *detailLogger) write(session, scenario string, start time.Time, custom *DetailCustom) {
+	now := time.Now()
+	line := DetailLog{
+		LogType:         "INFO",
+		Host:            l.host,
+		AppName:         l.appName,
+		Instance:        os.Getpid(),
+		Session:         session,
+		InitInvoke:      custom.Invoke,
+		Scenario:        scenario,
+		InputTimeStamp:  start.UTC().Format(time.RFC3339Nano),
+		OutputTimeStamp: now.UTC().Format(time.RFC3339Nano),
+		Custom:          custom,
+		ProcessingTime:  now.UTC().Format(time.RFC3339Nano),
+	}
+
+	data, err := json.Marshal(line)
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(append(data, '\n'))
+}
@@ -0,0 +1,272 @@
+package ms
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Encoder writes response bodies of one content type, see IApplication.RegisterEncoder.
+type Encoder interface {
+	ContentType() string
+	Encode(w io.Writer, v interface{}) error
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) ContentType() string { return "application/json" }
+
+func (jsonEncoder) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+type xmlEncoder struct{}
+
+func (xmlEncoder) ContentType() string { return "application/xml" }
+
+func (xmlEncoder) Encode(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
+
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) ContentType() string { return "application/msgpack" }
+
+func (msgpackEncoder) Encode(w io.Writer, v interface{}) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	return enc.Encode(v)
+}
+
+var (
+	JSONEncoder    Encoder = jsonEncoder{}
+	XMLEncoder     Encoder = xmlEncoder{}
+	MsgPackEncoder Encoder = msgpackEncoder{}
+)
+
+// encoderRegistry holds the encoders of an application, the first one is
+// used when the client does not ask for a known content type.
+type encoderRegistry struct {
+	encoders []Encoder
+}
+
+func newEncoderRegistry() *encoderRegistry {
+	return &encoderRegistry{encoders: []Encoder{JSONEncoder, XMLEncoder, MsgPackEncoder}}
+}
+
+func (r *encoderRegistry) register(encoder Encoder) {
+	for i, e := range r.encoders {
+		if e.ContentType() == encoder.ContentType() {
+			r.encoders[i] = encoder
+			return
+		}
+	}
+	r.encoders = append(r.encoders, encoder)
+}
+
+func (r *encoderRegistry) negotiate(accept string) Encoder {
+	for _, mediaType := range parseAccept(accept) {
+		for _, e := range r.encoders {
+			if matchMediaType(mediaType, e.ContentType()) {
+				return e
+			}
+		}
+	}
+	return r.encoders[0]
+}
+
+func matchMediaType(pattern, contentType string) bool {
+	if pattern == "*/*" || pattern == contentType {
+		return true
+	}
+
+	// application/x-msgpack, text/xml and friends
+	typ, sub, _ := strings.Cut(contentType, "/")
+	ptyp, psub, _ := strings.Cut(pattern, "/")
+	if psub == "*" {
+		return ptyp == typ
+	}
+	return strings.TrimPrefix(psub, "x-") == sub && (ptyp == typ || ptyp == "text")
+}
+
+// parseAccept returns the media types or codings of an Accept or
+// Accept-Encoding header, best quality first, without the q=0 ones.
+func parseAccept(header string) []string {
+	type item struct {
+		value string
+		q     float64
+	}
+
+	var items []item
+	for _, part := range strings.Split(header, ",") {
+		value, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if value == "" {
+			continue
+		}
+
+		q := 1.0
+		if _, p, err := mime.ParseMediaType("x/x;" + params); err == nil && p["q"] != "" {
+			if v, err := strconv.ParseFloat(p["q"], 64); err == nil {
+				q = v
+			}
+		}
+		if q > 0 {
+			items = append(items, item{strings.ToLower(strings.TrimSpace(value)), q})
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+
+	values := make([]string, len(items))
+	for i, it := range items {
+		values[i] = it.value
+	}
+	return values
+}
+
+type CompressionConfig struct {
+	Disabled bool
+	// MinSize is the smallest body compressed, default 1024 bytes.
+	MinSize int
+}
+
+// negotiateEncoding picks br or gzip from Accept-Encoding.
+func negotiateEncoding(acceptEncoding string) string {
+	for _, coding := range parseAccept(acceptEncoding) {
+		switch coding {
+		case "br", "gzip":
+			return coding
+		case "*":
+			return "gzip"
+		}
+	}
+	return ""
+}
+
+// output is how an application writes its responses.
+type output struct {
+	encoders    *encoderRegistry
+	compression CompressionConfig
+	heartbeat   time.Duration
+	client      *HTTPClient
+}
+
+func newOutput(cfg AppConfig) *output {
+	return &output{
+		encoders:    newEncoderRegistry(),
+		compression: cfg.Compression,
+		heartbeat:   cfg.StreamHeartbeat,
+		client:      NewHTTPClient(cfg.HTTPClient),
+	}
+}
+
+func (o *output) httpClient() *HTTPClient {
+	if o == nil {
+		return httpClientOf(nil)
+	}
+	return httpClientOf(o.client)
+}
+
+func (o *output) streamHeartbeat() time.Duration {
+	if o == nil {
+		return 0
+	}
+	return o.heartbeat
+}
+
+// writeResponse encodes data with the encoder negotiated from the Accept
+// header and compresses it according to Accept-Encoding. Data the encoder
+// fails on is sent as json, and a 500 is sent when json fails too, the
+// encoding error is returned then.
+func writeResponse(w http.ResponseWriter, r *http.Request, out *output, status int, data interface{}) error {
+	if !bodyAllowed(status) {
+		w.WriteHeader(status)
+		return nil
+	}
+
+	if out == nil {
+		out = newOutput(AppConfig{})
+	}
+	encoder := out.encoders.negotiate(r.Header.Get("Accept"))
+
+	var body bytes.Buffer
+	err := encoder.Encode(&body, data)
+	if err != nil && encoder != JSONEncoder {
+		// e.g. a map has no xml encoding
+		body.Reset()
+		encoder = JSONEncoder
+		err = encoder.Encode(&body, data)
+	}
+	if err != nil {
+		body.Reset()
+		status = http.StatusInternalServerError
+		encoder.Encode(&body, ErrorResponse{Status: status, Message: "response could not be encoded"})
+	}
+
+	return errors.Join(err, writeBody(w, r, out.compression, status, encoder.ContentType(), &body))
+}
+
+// writeBody writes the encoded body, compressed according to
+// Accept-Encoding.
+func writeBody(w http.ResponseWriter, r *http.Request, compression CompressionConfig, status int, contentType string, body *bytes.Buffer) error {
+	header := w.Header()
+	if strings.HasSuffix(contentType, "json") || strings.HasSuffix(contentType, "xml") {
+		contentType += "; charset=utf-8"
+	}
+	header.Set("Content-Type", contentType)
+	header.Add("Vary", "Accept")
+
+	minSize := compression.MinSize
+	if minSize <= 0 {
+		minSize = 1024
+	}
+
+	// the encoding may change with the size of the body, a cache keys on
+	// Accept-Encoding whatever it is
+	coding := ""
+	if !compression.Disabled {
+		header.Add("Vary", "Accept-Encoding")
+		if body.Len() >= minSize {
+			coding = negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		}
+	}
+
+	if coding == "" {
+		header.Set("Content-Length", strconv.Itoa(body.Len()))
+		w.WriteHeader(status)
+		_, err := w.Write(body.Bytes())
+		return err
+	}
+
+	header.Set("Content-Encoding", coding)
+	header.Del("Content-Length")
+	w.WriteHeader(status)
+
+	var cw io.WriteCloser
+	if coding == "br" {
+		cw = brotli.NewWriter(w)
+	} else {
+		cw = gzip.NewWriter(w)
+	}
+
+	if _, err := cw.Write(body.Bytes()); err != nil {
+		cw.Close()
+		return err
+	}
+	return cw.Close()
+}
@@ -0,0 +1,140 @@
+package ms
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type encodingProduct struct {
+	Name  string  `json:"name" xml:"name"`
+	Price float64 `json:"price" xml:"price"`
+}
+
+type csvEncoder struct{}
+
+func (csvEncoder) ContentType() string { return "text/csv" }
+
+func (csvEncoder) Encode(w io.Writer, v interface{}) error {
+	p := v.(encodingProduct)
+	_, err := io.WriteString(w, "name,price\n"+p.Name+",10\n")
+	return err
+}
+
+func TestResponseEncoding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	routes := func(app IApplication) {
+		app.RegisterEncoder(csvEncoder{})
+		app.Get("/product", func(ctx IContext) error {
+			return ctx.Response(http.StatusOK, encodingProduct{Name: "p1", Price: 10})
+		})
+		app.Get("/products", func(ctx IContext) error {
+			return ctx.Response(http.StatusOK, []encodingProduct{{Name: strings.Repeat("p", 2048), Price: 10}})
+		})
+		app.Get("/map", func(ctx IContext) error {
+			return ctx.Response(http.StatusOK, map[string]string{"name": "p1"})
+		})
+		app.Get("/invalid", func(ctx IContext) error {
+			ctx.Response(http.StatusOK, map[string]interface{}{"ch": make(chan int)})
+			return nil
+		})
+	}
+
+	mux := newMuxServer(Config{}).(*muxApplication)
+	routes(mux)
+	g := newGinServer(Config{}).(*ginApplication)
+	routes(g)
+
+	for name, router := range map[string]http.Handler{"mux": mux.mux, "gin": g.router} {
+		serve := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+			for k, v := range headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			return w
+		}
+
+		t.Run(name+"/DefaultJSON", func(t *testing.T) {
+			w := serve("/product", nil)
+			assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+			assert.JSONEq(t, `{"name":"p1","price":10}`, w.Body.String())
+		})
+
+		t.Run(name+"/XML", func(t *testing.T) {
+			w := serve("/product", map[string]string{"Accept": "application/json;q=0.5, text/xml"})
+			assert.Equal(t, "application/xml; charset=utf-8", w.Header().Get("Content-Type"))
+
+			var p encodingProduct
+			assert.NoError(t, xml.Unmarshal(w.Body.Bytes(), &p))
+			assert.Equal(t, "p1", p.Name)
+		})
+
+		t.Run(name+"/MessagePack", func(t *testing.T) {
+			w := serve("/product", map[string]string{"Accept": "application/x-msgpack"})
+			assert.Equal(t, "application/msgpack", w.Header().Get("Content-Type"))
+
+			var p map[string]interface{}
+			assert.NoError(t, msgpack.Unmarshal(w.Body.Bytes(), &p))
+			assert.Equal(t, "p1", p["name"])
+		})
+
+		t.Run(name+"/CustomEncoder", func(t *testing.T) {
+			w := serve("/product", map[string]string{"Accept": "text/csv"})
+			assert.Equal(t, "name,price\np1,10\n", w.Body.String())
+		})
+
+		t.Run(name+"/Gzip", func(t *testing.T) {
+			w := serve("/products", map[string]string{"Accept-Encoding": "gzip, br;q=0.5"})
+			assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+
+			reader, err := gzip.NewReader(w.Body)
+			assert.NoError(t, err)
+			var products []encodingProduct
+			assert.NoError(t, json.NewDecoder(reader).Decode(&products))
+			assert.Len(t, products, 1)
+		})
+
+		t.Run(name+"/Brotli", func(t *testing.T) {
+			w := serve("/products", map[string]string{"Accept-Encoding": "br"})
+			assert.Equal(t, "br", w.Header().Get("Content-Encoding"))
+
+			var products []encodingProduct
+			assert.NoError(t, json.NewDecoder(brotli.NewReader(w.Body)).Decode(&products))
+			assert.Len(t, products, 1)
+		})
+
+		t.Run(name+"/SmallBodyNotCompressed", func(t *testing.T) {
+			w := serve("/product", map[string]string{"Accept-Encoding": "gzip"})
+			assert.Empty(t, w.Header().Get("Content-Encoding"))
+			// a cache must not serve it for a larger, compressed body
+			assert.Contains(t, w.Header().Values("Vary"), "Accept-Encoding")
+		})
+
+		t.Run(name+"/FallbackJSON", func(t *testing.T) {
+			// a map has no xml encoding
+			w := serve("/map", map[string]string{"Accept": "application/xml"})
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+			assert.JSONEq(t, `{"name":"p1"}`, w.Body.String())
+		})
+
+		t.Run(name+"/EncodeError", func(t *testing.T) {
+			w := serve("/invalid", nil)
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			assert.JSONEq(t, `{"success":false,"status":500,"message":"response could not be encoded"}`, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package ms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sing3demons/product-service/db"
+)
+
+// Error carries the HTTP status a handler wants to answer with.
+type Error struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Err     error  `json:"-"`
+}
+
+func NewError(status int, message string) *Error {
+	return &Error{Status: status, Message: message}
+}
+
+func WrapError(status int, err error) *Error {
+	return &Error{Status: status, Message: err.Error(), Err: err}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) StatusCode() int {
+	return e.Status
+}
+
+// StatusCoder is implemented by responses and errors that pick their own status code.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+type ErrorResponse struct {
+	Success bool         `json:"success"`
+	Status  int          `json:"status"`
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors,omitempty"`
+}
+
+func statusFromError(err error) int {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return http.StatusBadRequest
+	}
+
+	var coder StatusCoder
+	if errors.As(err, &coder) {
+		return coder.StatusCode()
+	}
+
+	var bindErr *BindError
+	if errors.As(err, &bindErr) {
+		return http.StatusBadRequest
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+
+	return http.StatusInternalServerError
+}
+
+// ErrorMessage is the message of err to send to the client with status. Only
+// the validation, bind and status errors of a 4xx tell their own message, any
+// other error is the text of status so its details stay in the logs.
+func ErrorMessage(status int, err error) string {
+	if status >= http.StatusInternalServerError {
+		return http.StatusText(status)
+	}
+	var validationErr *ValidationError
+	var bindErr *BindError
+	var coder StatusCoder
+	if errors.As(err, &validationErr) || errors.As(err, &bindErr) || errors.As(err, &coder) {
+		return err.Error()
+	}
+	return http.StatusText(status)
+}
+
+func newErrorResponse(err error) ErrorResponse {
+	status := statusFromError(err)
+	response := ErrorResponse{
+		Success: false,
+		Status:  status,
+		Message: ErrorMessage(status, err),
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		response.Message = validationErr.Message
+		response.Errors = validationErr.Errors
+	}
+
+	// the query parameters of a db.QuerySchema, listed like the fields of a payload
+	var queryErr *db.QueryError
+	if errors.As(err, &queryErr) {
+		response.Message = queryErr.Message
+		for _, e := range queryErr.Errors {
+			response.Errors = append(response.Errors, FieldError(e))
+		}
+	}
+
+	return response
+}
+
+// errorResponse answers ctx with the ErrorResponse of err, logging the server
+// errors as their message is not sent.
+func errorResponse(ctx IContext, err error) error {
+	response := newErrorResponse(err)
+	if response.Status >= http.StatusInternalServerError {
+		ctx.Log(fmt.Sprintf("error %d: %v", response.Status, err))
+	}
+	return ctx.Response(response.Status, response)
+}
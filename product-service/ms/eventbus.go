@@ -0,0 +1,65 @@
+package ms
+
+import "sync"
+
+type Event struct {
+	Topic string
+	Data  interface{}
+}
+
+// EventBus fans events out to the subscribers of a topic within the process,
+// e.g. from a Kafka consumer or a handler to SSE streams.
+type EventBus interface {
+	Publish(topic string, data interface{})
+	// Subscribe returns the events of topic until cancel is called.
+	Subscribe(topic string) (events <-chan Event, cancel func())
+}
+
+type eventBus struct {
+	mu     sync.RWMutex
+	subs   map[string]map[chan Event]struct{}
+	buffer int
+}
+
+// NewEventBus returns an in-memory EventBus. Each subscriber buffers buffer
+// events, events are dropped for subscribers that fall behind.
+func NewEventBus(buffer int) EventBus {
+	if buffer <= 0 {
+		buffer = 16
+	}
+	return &eventBus{subs: map[string]map[chan Event]struct{}{}, buffer: buffer}
+}
+
+func (b *eventBus) Publish(topic string, data interface{}) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	event := Event{Topic: topic, Data: data}
+	for ch := range b.subs[topic] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (b *eventBus) Subscribe(topic string) (<-chan Event, func()) {
+	ch := make(chan Event, b.buffer)
+
+	b.mu.Lock()
+	if b.subs[topic] == nil {
+		b.subs[topic] = map[chan Event]struct{}{}
+	}
+	b.subs[topic][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs[topic], ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package ms
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var ErrGRPCUnsupported = errors.New("not supported on grpc")
+
+type GRPCConfig struct {
+	// Port of the gRPC server, it starts with Start once a service is registered.
+	Port string
+}
+
+// grpcServer runs the gRPC services of an application. Every call goes
+// through the logging and recovery interceptors, then the UseGRPC middlewares.
+type grpcServer struct {
+	cfg         GRPCConfig
+	broker      Broker
+	client      *HTTPClient
+	server      *grpc.Server
+	middlewares []Middleware
+	registered  bool
+}
+
+func newGRPCServer(cfg GRPCConfig, broker Broker, client *HTTPClient) *grpcServer {
+	g := &grpcServer{cfg: cfg, broker: broker, client: client}
+	g.server = grpc.NewServer(
+		grpc.UnaryInterceptor(g.unary),
+		grpc.StreamInterceptor(g.stream),
+	)
+	return g
+}
+
+func (g *grpcServer) register(desc *grpc.ServiceDesc, impl interface{}) {
+	g.server.RegisterService(desc, impl)
+	g.registered = true
+}
+
+func (g *grpcServer) use(middlewares ...Middleware) {
+	g.middlewares = append(g.middlewares, middlewares...)
+}
+
+func (g *grpcServer) serve() error {
+	if !g.registered || g.cfg.Port == "" {
+		return nil
+	}
+
+	lis, err := net.Listen("tcp", ":"+g.cfg.Port)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Start grpc server: %s\n", lis.Addr())
+	return g.server.Serve(lis)
+}
+
+// shutdown waits for the running calls until ctx is done.
+func (g *grpcServer) shutdown(ctx context.Context) {
+	if !g.registered {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		g.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		g.server.Stop()
+	}
+}
+
+func (g *grpcServer) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	var res interface{}
+	err := g.call(ctx, info.FullMethod, func(ctx context.Context) error {
+		var err error
+		res, err = handler(ctx, req)
+		return err
+	})
+	return res, err
+}
+
+func (g *grpcServer) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return g.call(ss.Context(), info.FullMethod, func(ctx context.Context) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	})
+}
+
+// call runs fn behind the middlewares. A middleware answering with
+// ctx.Response instead of calling next ends the call with the matching code.
+func (g *grpcServer) call(ctx context.Context, method string, fn func(ctx context.Context) error) (err error) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("grpc %s panic: %v\n%s", method, r, debug.Stack())
+			err = status.Error(codes.Internal, http.StatusText(http.StatusInternalServerError))
+		}
+		log.Printf("grpc %s %s %s\n", method, status.Code(err), time.Since(start))
+	}()
+
+	c := newGRPCContext(ctx, method, g.broker, g.client)
+	final := func(c IContext) error {
+		return fn(context.WithValue(c.Context(), grpcContextKey{}, c))
+	}
+	if err := preHandle(final, g.middlewares...)(c); err != nil {
+		return grpcError(method, err)
+	}
+	if c.status >= http.StatusBadRequest {
+		return status.Error(grpcCode(c.status), responseMessage(c.status, c.data))
+	}
+	return nil
+}
+
+type grpcContextKey struct{}
+
+// GRPCContextFrom is the context of the gRPC call of ctx, for the handlers to
+// send messages with SendMessage, nil out of a call.
+func GRPCContextFrom(ctx context.Context) IContext {
+	c, _ := ctx.Value(grpcContextKey{}).(IContext)
+	return c
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// grpcError converts the errors of handlers and middlewares, gRPC status
+// errors are returned as is. The server errors are logged as their message is
+// not sent.
+func grpcError(method string, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	response := newErrorResponse(err)
+	if response.Status >= http.StatusInternalServerError {
+		log.Printf("grpc %s error: %v\n", method, err)
+	}
+	return status.Error(grpcCode(response.Status), response.Message)
+}
+
+func responseMessage(code int, data interface{}) string {
+	switch v := data.(type) {
+	case ErrorResponse:
+		return v.Message
+	case error:
+		return ErrorMessage(code, v)
+	case string:
+		return v
+	}
+	return http.StatusText(code)
+}
+
+// grpcCode maps an http status to the closest gRPC code.
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+
+	switch {
+	case httpStatus < http.StatusBadRequest:
+		return codes.OK
+	case httpStatus >= http.StatusInternalServerError:
+		return codes.Internal
+	}
+	return codes.Unknown
+}
+
+// GRPCContext is the context middlewares get for a gRPC call. Header reads
+// the incoming metadata, Method is the full method name, e.g.
+// "/product.v1.ProductService/CreateProduct".
+type GRPCContext struct {
+	ctx    context.Context
+	method string
+	broker Broker
+	client *HTTPClient
+	values map[string]interface{}
+	status int
+	data   interface{}
+}
+
+func newGRPCContext(ctx context.Context, method string, broker Broker, client *HTTPClient) *GRPCContext {
+	return &GRPCContext{ctx: ctx, method: method, broker: broker, client: client}
+}
+
+func (c *GRPCContext) Context() context.Context {
+	return c.ctx
+}
+
+func (c *GRPCContext) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+func (c *GRPCContext) Log(message string) {
+	log.Println("Context:", message)
+}
+
+func (c *GRPCContext) Param(name string) string {
+	return ""
+}
+
+func (c *GRPCContext) Query(name string) string {
+	return ""
+}
+
+func (c *GRPCContext) Method() string {
+	return c.method
+}
+
+func (c *GRPCContext) Header(name string) string {
+	values := metadata.ValueFromIncomingContext(c.ctx, strings.ToLower(name))
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c *GRPCContext) SetHeader(name, value string) {
+	grpc.SetHeader(c.ctx, metadata.Pairs(name, value))
+}
+
+func (c *GRPCContext) ClientIP() string {
+	p, ok := peer.FromContext(c.ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func (c *GRPCContext) Set(key string, value interface{}) {
+	if c.values == nil {
+		c.values = map[string]interface{}{}
+	}
+	c.values[key] = value
+}
+
+func (c *GRPCContext) Get(key string) interface{} {
+	return c.values[key]
+}
+
+func (c *GRPCContext) Claims() *Claims {
+	return claimsFromContext(c)
+}
+
+// ReadInput is not supported, the request is decoded by the gRPC handler.
+func (c *GRPCContext) ReadInput(data interface{}) error {
+	return ErrGRPCUnsupported
+}
+
+// Response ends the call when a middleware answers instead of calling next,
+// an error status becomes the gRPC status of the call.
+func (c *GRPCContext) Response(responseCode int, responseData interface{}) error {
+	c.status = responseCode
+	c.data = responseData
+	return nil
+}
+
+func (c *GRPCContext) Stream(fn func(w SSEWriter) error) error {
+	return ErrStreamUnsupported
+}
+
+func (c *GRPCContext) SendMessage(topic string, message interface{}, opts ...OptionProducerMessage) error {
+	return sendMessage(c, c.broker, topic, message, opts)
+}
+
+func (c *GRPCContext) Call(req HTTPRequest) (*HTTPResponse, error) {
+	return httpClientOf(c.client).Do(c, req)
+}
+
+func (c *GRPCContext) CallAll(reqs ...HTTPRequest) ([]*HTTPResponse, error) {
+	return httpClientOf(c.client).DoAll(c, reqs...)
+}
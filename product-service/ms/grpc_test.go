@@ -0,0 +1,80 @@
+package ms
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestGRPCMiddleware(t *testing.T) {
+	app := newMuxServer(Config{}).(*muxApplication)
+	healthpb.RegisterHealthServer(app, health.NewServer())
+	app.UseGRPC(func(next HandleFunc) HandleFunc {
+		return func(ctx IContext) error {
+			if ctx.Header("Authorization") != "secret" {
+				return ctx.Response(http.StatusUnauthorized, ErrorResponse{Message: "missing token"})
+			}
+			return next(ctx)
+		}
+	})
+
+	lis := bufconn.Listen(1 << 20)
+	go app.grpc.server.Serve(lis)
+	defer app.grpc.server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Equal(t, "missing token", status.Convert(err).Message())
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "secret")
+	res, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.Status)
+}
+
+func TestGRPCError(t *testing.T) {
+	method := "/product.ProductService/GetProduct"
+	assert.Equal(t, codes.NotFound, status.Code(grpcError(method, NewError(http.StatusNotFound, "not found"))))
+	assert.Equal(t, codes.InvalidArgument, status.Code(grpcError(method, &ValidationError{Message: "invalid"})))
+	assert.Equal(t, codes.Internal, status.Code(grpcError(method, context.Canceled)))
+	assert.Equal(t, codes.Aborted, status.Code(grpcError(method, status.Error(codes.Aborted, "aborted"))))
+	// the details of a server error stay in the logs
+	internal, _ := status.FromError(grpcError(method, errors.New("dial tcp 10.0.0.5:27017: connection refused")))
+	assert.Equal(t, http.StatusText(http.StatusInternalServerError), internal.Message())
+}
+
+func TestGRPCContextFrom(t *testing.T) {
+	broker := NewMemoryBroker()
+	g := newGRPCServer(GRPCConfig{}, broker, nil)
+	assert.Nil(t, GRPCContextFrom(context.Background()))
+
+	err := g.call(context.Background(), "/product.v1.ProductService/CreateProduct", func(ctx context.Context) error {
+		c := GRPCContextFrom(ctx)
+		if assert.NotNil(t, c) {
+			assert.Equal(t, "/product.v1.ProductService/CreateProduct", c.Method())
+			assert.NoError(t, c.SendMessage("products", "created", WithHeader("X-Source", "a")))
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+}
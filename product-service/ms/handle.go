@@ -0,0 +1,195 @@
+package ms
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// bodyReader decodes the request body or message without validating it,
+// so that path and query values can be bound before validation runs.
+// Contexts without it, e.g. test fakes, are read with ReadInput.
+type bodyReader interface {
+	ReadBody(data interface{}) error
+}
+
+// BindError reports a path or query value that could not be converted to its field type.
+type BindError struct {
+	Source string
+	Field  string
+	Value  string
+	Err    error
+}
+
+func (e *BindError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("invalid %s: %v", e.Source, e.Err)
+	}
+	return fmt.Sprintf("invalid %s %s=%q: %v", e.Source, e.Field, e.Value, e.Err)
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// Handle adapts a typed function to a HandleFunc. The request is bound from the
+// body (json), `path:"id"` and `query:"search"` tags, then validated. The result
+// is sent with status 200 unless it implements StatusCoder; errors are mapped to
+// a status code and sent as an ErrorResponse.
+//
+//	app.Get("/products/{id}", ms.Handle(func(ctx ms.IContext, req GetProductRequest) (model.Product, error) {
+//		...
+//	}))
+func Handle[Req, Res any](fn func(ctx IContext, req Req) (Res, error)) HandleFunc {
+	handler := func(ctx IContext) error {
+		var req Req
+		if err := bind(ctx, &req); err != nil {
+			return errorResponse(ctx, err)
+		}
+
+		res, err := fn(ctx, req)
+		if err != nil {
+			return errorResponse(ctx, err)
+		}
+
+		status := http.StatusOK
+		if coder, ok := any(res).(StatusCoder); ok && coder.StatusCode() != 0 {
+			status = coder.StatusCode()
+		}
+
+		return ctx.Response(status, res)
+	}
+	describe(handler, handlerDoc{req: reflect.TypeOf((*Req)(nil)).Elem(), res: reflect.TypeOf((*Res)(nil)).Elem()})
+	return handler
+}
+
+// HandleMessage is the consumer counterpart of Handle: the message is bound and
+// validated into Req, and the handler error is returned so the message is not
+// marked as consumed.
+func HandleMessage[Req any](fn func(ctx IContext, req Req) error) ServiceHandleFunc {
+	return func(ctx IContext) error {
+		var req Req
+		if err := bind(ctx, &req); err != nil {
+			return err
+		}
+
+		return fn(ctx, req)
+	}
+}
+
+func bind(ctx IContext, req interface{}) error {
+	if reader, ok := ctx.(bodyReader); ok {
+		if err := reader.ReadBody(req); err != nil && !errors.Is(err, io.EOF) {
+			if errors.Is(err, ErrBodyTooLarge) {
+				return err
+			}
+			return &BindError{Source: "body", Err: err}
+		}
+	} else if err := ctx.ReadInput(req); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	val := reflect.ValueOf(req).Elem()
+	if val.Kind() == reflect.Struct {
+		if err := bindValues(ctx, val); err != nil {
+			return err
+		}
+	}
+
+	return validateInput(req)
+}
+
+func bindValues(ctx IContext, val reflect.Value) error {
+	typ := val.Type()
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		fieldType := typ.Field(i)
+
+		if fieldType.Anonymous && field.Kind() == reflect.Struct {
+			if err := bindValues(ctx, field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !fieldType.IsExported() {
+			continue
+		}
+
+		source, name := "path", fieldType.Tag.Get("path")
+		value := ""
+		if name != "" {
+			value = ctx.Param(name)
+		} else if name = fieldType.Tag.Get("query"); name != "" {
+			source = "query"
+			value = ctx.Query(name)
+		}
+
+		if name == "" || value == "" {
+			continue
+		}
+
+		if err := setValue(field, value); err != nil {
+			return &BindError{Source: source, Field: name, Value: value, Err: err}
+		}
+	}
+	return nil
+}
+
+// setValue converts a path or query string to the kind of field.
+// Slices take comma separated values.
+func setValue(field reflect.Value, value string) error {
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setValue(ptr.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(v)
+	case reflect.Slice:
+		parts := strings.Split(value, ",")
+		slice := reflect.MakeSlice(field.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setValue(slice.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
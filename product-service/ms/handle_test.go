@@ -0,0 +1,160 @@
+package ms
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sing3demons/product-service/db"
+	"github.com/stretchr/testify/assert"
+)
+
+type handleRequest struct {
+	ID     string   `path:"id" validate:"required"`
+	Limit  int      `query:"limit"`
+	Fields []string `query:"fields"`
+	Name   string   `json:"name" validate:"required"`
+}
+
+type handleResponse struct {
+	ID     string   `json:"id"`
+	Limit  int      `json:"limit"`
+	Fields []string `json:"fields"`
+	Name   string   `json:"name"`
+}
+
+func handleRoutes(app IApplication) {
+	app.Post("/items/{id}", Handle(func(ctx IContext, req handleRequest) (handleResponse, error) {
+		if req.ID == "missing" {
+			return handleResponse{}, NewError(http.StatusNotFound, "item not found")
+		}
+		if req.ID == "query" {
+			_, err := db.NewQuerySchema(handleResponse{}).Parse(ctx.Query)
+			return handleResponse{}, err
+		}
+		if req.ID == "broken" {
+			return handleResponse{}, errors.New("dial tcp 10.0.0.5:27017: connection refused")
+		}
+		return handleResponse{ID: req.ID, Limit: req.Limit, Fields: req.Fields, Name: req.Name}, nil
+	}))
+}
+
+func TestHandle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	routers := map[string]http.Handler{}
+
+	mux := newMuxServer(Config{}).(*muxApplication)
+	handleRoutes(mux)
+	routers["mux"] = mux.mux
+
+	g := newGinServer(Config{}).(*ginApplication)
+	handleRoutes(g)
+	routers["gin"] = g.router
+
+	for name, router := range routers {
+		t.Run(name+"/Bind", func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/items/42?limit=5&fields=name,price", strings.NewReader(`{"name":"p1"}`))
+			router.ServeHTTP(w, r)
+
+			var res handleResponse
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+			assert.Equal(t, handleResponse{ID: "42", Limit: 5, Fields: []string{"name", "price"}, Name: "p1"}, res)
+		})
+
+		t.Run(name+"/Validation", func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/items/42", strings.NewReader(`{}`))
+			router.ServeHTTP(w, r)
+
+			var res ErrorResponse
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+			assert.Len(t, res.Errors, 1)
+			assert.Equal(t, "name", res.Errors[0].Field)
+		})
+
+		t.Run(name+"/BadQuery", func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/items/42?limit=abc", strings.NewReader(`{"name":"p1"}`))
+			router.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+
+		t.Run(name+"/Error", func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/items/missing", strings.NewReader(`{"name":"p1"}`))
+			router.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusNotFound, w.Code)
+			assert.Contains(t, w.Body.String(), "item not found")
+		})
+
+		t.Run(name+"/QueryError", func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/items/query?sort=secret", strings.NewReader(`{"name":"p1"}`))
+			router.ServeHTTP(w, r)
+
+			var res ErrorResponse
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+			assert.Equal(t, "invalid query", res.Message)
+			if assert.Len(t, res.Errors, 1) {
+				assert.Equal(t, "sort", res.Errors[0].Field)
+			}
+		})
+
+		t.Run(name+"/ServerError", func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/items/broken", strings.NewReader(`{"name":"p1"}`))
+			router.ServeHTTP(w, r)
+
+			var res ErrorResponse
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+			assert.Equal(t, http.StatusText(http.StatusInternalServerError), res.Message)
+		})
+	}
+}
+
+func TestHandleMessage(t *testing.T) {
+	h := HandleMessage(func(ctx IContext, req handleResponse) error {
+		assert.Equal(t, "p1", req.Name)
+		return nil
+	})
+
+	ctx := NewConsumerContext(nil, &Message{Value: []byte(`{"name":"p1"}`)})
+	assert.NoError(t, h(ctx))
+
+	invalid := HandleMessage(func(ctx IContext, req handleRequest) error { return nil })
+	ctx = NewConsumerContext(nil, &Message{Value: []byte(`{"name":"p1"}`)})
+	assert.Error(t, invalid(ctx))
+}
+
+func TestQuerySemicolon(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	echo := func(ctx IContext) error {
+		return ctx.Response(http.StatusOK, ctx.Query("filter")+"|"+ctx.Query("page"))
+	}
+
+	mux := newMuxServer(Config{}).(*muxApplication)
+	mux.Get("/items", echo)
+	g := newGinServer(Config{}).(*ginApplication)
+	g.Get("/items", echo)
+
+	for name, router := range map[string]http.Handler{"mux": mux.mux, "gin": g.router} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items?filter=price=gt=10;name==a%20b&page=2", nil))
+
+		var res string
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&res), name)
+		assert.Equal(t, "price=gt=10;name==a b|2", res, name)
+	}
+}
@@ -0,0 +1,82 @@
+package ms
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type HealthStatus struct {
+	// Status is UP, or DEGRADED while a circuit breaker is not closed.
+	Status    string          `json:"status"`
+	Breakers  []BreakerStats  `json:"breakers"`
+	Bulkheads []BulkheadStats `json:"bulkheads"`
+}
+
+// Health answers the state of the circuit breakers and bulkheads. It answers
+// 200 when degraded too, since the service still serves what does not need
+// the broken dependency.
+//
+//	app.Get("/health", ms.Health())
+func Health() HandleFunc {
+	return func(ctx IContext) error {
+		breakers, bulkheads := resilience.stats()
+		status := HealthStatus{Status: "UP", Breakers: breakers, Bulkheads: bulkheads}
+		for _, b := range breakers {
+			if b.state != BreakerClosed {
+				status.Status = "DEGRADED"
+			}
+		}
+		return ctx.Response(http.StatusOK, status)
+	}
+}
+
+// Metrics answers the state of the circuit breakers and bulkheads in the
+// Prometheus text format, or as json outside of an HTTP request.
+//
+//	app.Get("/metrics", ms.Metrics())
+func Metrics() HandleFunc {
+	return func(ctx IContext) error {
+		breakers, bulkheads := resilience.stats()
+		hc, ok := ctx.(httpContext)
+		if !ok {
+			return ctx.Response(http.StatusOK, HealthStatus{Breakers: breakers, Bulkheads: bulkheads})
+		}
+
+		var b strings.Builder
+		metric := func(name, kind, help string) {
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		}
+
+		metric("ms_circuit_breaker_state", "gauge", "Circuit breaker state, 0 closed, 1 half-open, 2 open.")
+		for _, s := range breakers {
+			fmt.Fprintf(&b, "ms_circuit_breaker_state{name=%q} %d\n", s.Name, s.state)
+		}
+		metric("ms_circuit_breaker_failures_total", "counter", "Failed calls through the circuit breaker.")
+		for _, s := range breakers {
+			fmt.Fprintf(&b, "ms_circuit_breaker_failures_total{name=%q} %d\n", s.Name, s.Failures)
+		}
+		metric("ms_circuit_breaker_rejected_total", "counter", "Calls rejected by the open circuit breaker.")
+		for _, s := range breakers {
+			fmt.Fprintf(&b, "ms_circuit_breaker_rejected_total{name=%q} %d\n", s.Name, s.Rejected)
+		}
+		metric("ms_bulkhead_in_flight", "gauge", "Calls running through the bulkhead.")
+		for _, s := range bulkheads {
+			fmt.Fprintf(&b, "ms_bulkhead_in_flight{name=%q} %d\n", s.Name, s.InFlight)
+		}
+		metric("ms_bulkhead_capacity", "gauge", "Concurrent calls allowed by the bulkhead.")
+		for _, s := range bulkheads {
+			fmt.Fprintf(&b, "ms_bulkhead_capacity{name=%q} %d\n", s.Name, s.Capacity)
+		}
+		metric("ms_bulkhead_rejected_total", "counter", "Calls rejected by the full bulkhead.")
+		for _, s := range bulkheads {
+			fmt.Fprintf(&b, "ms_bulkhead_rejected_total{name=%q} %d\n", s.Name, s.Rejected)
+		}
+
+		w, _ := hc.httpRequest()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte(b.String()))
+		return err
+	}
+}
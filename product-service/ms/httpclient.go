@@ -0,0 +1,448 @@
+package ms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultPropagateHeaders are the session and trace headers an HTTPClient
+// copies from the incoming request to the calls it makes.
+var DefaultPropagateHeaders = []string{
+	"X-Session",
+	"Session-Id",
+	"X-Request-Id",
+	"Traceparent",
+	"Tracestate",
+}
+
+type HTTPClientConfig struct {
+	// Timeout bounds each attempt of a call, default 10s.
+	Timeout time.Duration
+	// Retries is how many times an idempotent call is sent again after a
+	// network error, a 429 or a 5xx, default 2, -1 disables retries.
+	Retries int
+	// Backoff is the delay before the first retry, doubled for each next one
+	// up to MaxBackoff, default 100ms and 2s. A Retry-After header is
+	// honoured up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// PropagateHeaders are copied from the incoming request, default
+	// DefaultPropagateHeaders. The Authorization header is only sent when
+	// listed here, so tokens are not leaked to third parties.
+	PropagateHeaders []string
+	// Guard puts every host called behind its own circuit breaker and
+	// bulkhead, named "http:<host>". Transport errors and 5xx are failures.
+	Guard GuardConfig
+	// AppName names the service in the detail log.
+	AppName string
+	// Log receives the detail log of the calls, default stdout.
+	Log       io.Writer
+	Transport http.RoundTripper
+}
+
+// HTTPRequest is an outbound call made with IContext.Call.
+type HTTPRequest struct {
+	Method string // default GET
+	// URL may hold {name} placeholders, replaced by the escaped Params.
+	URL     string
+	Params  map[string]string
+	Query   map[string]string
+	Headers map[string]string
+	// Body is sent as is when it is a []byte or string, otherwise as json.
+	Body interface{}
+	// Timeout overrides HTTPClientConfig.Timeout for this call.
+	Timeout time.Duration
+	// Retry retries a non idempotent method too, e.g. a POST carrying an
+	// idempotency key.
+	Retry bool
+	// Service and Command name the call in the detail log, e.g.
+	// "inventory" and "get_stock".
+	Service string
+	Command string
+}
+
+// HTTPResponse is the answer of a call, any status included. Err is set
+// instead for the calls of CallAll that failed.
+type HTTPResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	Err    error
+}
+
+// OK reports a 2xx status.
+func (r *HTTPResponse) OK() bool {
+	return r.Err == nil && r.Status >= 200 && r.Status < 300
+}
+
+// Decode reads the json body into v.
+func (r *HTTPResponse) Decode(v interface{}) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	return json.Unmarshal(r.Body, v)
+}
+
+// HTTPClient calls other services with timeouts, retries and the session
+// headers of the request being served, logging every call.
+type HTTPClient struct {
+	cfg    HTTPClientConfig
+	client *http.Client
+	log    *detailLogger
+
+	mu     sync.Mutex
+	guards map[string]*Guard
+}
+
+func NewHTTPClient(cfg HTTPClientConfig) *HTTPClient {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.Retries == 0 {
+		cfg.Retries = 2
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = 100 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 2 * time.Second
+	}
+	if cfg.PropagateHeaders == nil {
+		cfg.PropagateHeaders = DefaultPropagateHeaders
+	}
+	transport := cfg.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &HTTPClient{
+		cfg:    cfg,
+		client: &http.Client{Transport: transport},
+		log:    newDetailLogger(cfg.Log, cfg.AppName),
+		guards: map[string]*Guard{},
+	}
+}
+
+var defaultHTTPClient = sync.OnceValue(func() *HTTPClient {
+	return NewHTTPClient(HTTPClientConfig{})
+})
+
+// httpClientOf is client, or the default client of contexts built outside an
+// application.
+func httpClientOf(client *HTTPClient) *HTTPClient {
+	if client == nil {
+		return defaultHTTPClient()
+	}
+	return client
+}
+
+// Do sends req on behalf of ctx. The error reports a call that got no
+// response, a response of any status is returned as is.
+func (c *HTTPClient) Do(ctx IContext, req HTTPRequest) (*HTTPResponse, error) {
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	target, err := requestURL(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := requestBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	for _, name := range c.cfg.PropagateHeaders {
+		if value := ctx.Header(name); value != "" {
+			header.Set(name, value)
+		}
+	}
+	for name, value := range req.Headers {
+		header.Set(name, value)
+	}
+	if body != nil && header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "application/json")
+	}
+
+	timeout := c.cfg.Timeout
+	if req.Timeout > 0 {
+		timeout = req.Timeout
+	}
+	retries := 0
+	if c.cfg.Retries > 0 && (req.Retry || idempotent(method)) {
+		retries = c.cfg.Retries
+	}
+
+	call := &outboundCall{
+		session:  session(ctx),
+		invoke:   uuid.NewString(),
+		scenario: req.Service + "." + req.Command,
+		method:   method,
+	}
+
+	for attempt := 0; ; attempt++ {
+		res, err := c.send(ctx.Context(), call, method, target, header, body, timeout)
+		if attempt >= retries || !retryable(ctx.Context(), res, err) {
+			return res, err
+		}
+
+		select {
+		case <-time.After(c.backoff(attempt, res)):
+		case <-ctx.Context().Done():
+			return nil, ctx.Context().Err()
+		}
+	}
+}
+
+// DoAll sends reqs concurrently. The responses are in the order of reqs,
+// the failed calls carry their Err, joined in the returned error.
+func (c *HTTPClient) DoAll(ctx IContext, reqs ...HTTPRequest) ([]*HTTPResponse, error) {
+	responses := make([]*HTTPResponse, len(reqs))
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		wg.Add(1)
+		go func(i int, req HTTPRequest) {
+			defer wg.Done()
+			res, err := c.Do(ctx, req)
+			if err != nil {
+				res = &HTTPResponse{Err: fmt.Errorf("%s %s: %w", req.Method, req.URL, err)}
+			}
+			responses[i] = res
+		}(i, req)
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, res := range responses {
+		if res.Err != nil {
+			errs = append(errs, res.Err)
+		}
+	}
+	return responses, errors.Join(errs...)
+}
+
+type outboundCall struct {
+	session  string
+	invoke   string
+	scenario string
+	method   string
+}
+
+func (c *HTTPClient) send(ctx context.Context, call *outboundCall, method, target string, header http.Header, body []byte, timeout time.Duration) (*HTTPResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	r, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	r.Header = header.Clone()
+
+	start := time.Now()
+	c.logCall(call, start, "Request", map[string]interface{}{
+		"Header": redactHeader(r.Header),
+		"Url":    target,
+		"Body":   rawBody(body),
+	})
+
+	var response *HTTPResponse
+	err = c.guard(r.URL.Host).Do(ctx, func() error {
+		response, err = c.roundTrip(r)
+		if err == nil && response.Status >= 500 {
+			// counted by the breaker, the response is returned as is
+			return errServerError
+		}
+		return err
+	})
+	if errors.Is(err, errServerError) {
+		err = nil
+	}
+	if err != nil {
+		data := map[string]interface{}{"Error": err.Error()}
+		if response != nil {
+			data["Status"] = response.Status
+		}
+		c.logCall(call, start, "Response", data)
+		return nil, err
+	}
+
+	c.logCall(call, start, "Response", map[string]interface{}{
+		"Header": redactHeader(response.Header),
+		"Status": response.Status,
+		"Body":   rawBody(response.Body),
+	})
+	return response, nil
+}
+
+var errServerError = errors.New("server error")
+
+// sensitiveHeaders hold credentials, they are not written to the detail log.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+	"Api-Key":             true,
+	"X-Auth-Token":        true,
+	"X-Csrf-Token":        true,
+}
+
+// redactHeader is header for the detail log, the values of the sensitive
+// headers replaced.
+func redactHeader(header http.Header) http.Header {
+	redacted := header.Clone()
+	for key := range redacted {
+		if sensitiveHeaders[http.CanonicalHeaderKey(key)] {
+			redacted[key] = []string{"[REDACTED]"}
+		}
+	}
+	return redacted
+}
+
+func (c *HTTPClient) roundTrip(r *http.Request) (*HTTPResponse, error) {
+	res, err := c.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return &HTTPResponse{Status: res.StatusCode, Header: res.Header}, err
+	}
+	return &HTTPResponse{Status: res.StatusCode, Header: res.Header, Body: data}, nil
+}
+
+// guard is the Guard of the calls to host, nil when the config has none.
+func (c *HTTPClient) guard(host string) *Guard {
+	if c.cfg.Guard.Breaker == nil && c.cfg.Guard.Bulkhead == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	g, ok := c.guards[host]
+	if !ok {
+		g = NewGuard("http:"+host, c.cfg.Guard)
+		c.guards[host] = g
+	}
+	return g
+}
+
+func (c *HTTPClient) logCall(call *outboundCall, start time.Time, kind string, data map[string]interface{}) {
+	raw, _ := json.Marshal(data)
+	c.log.write(call.session, call.scenario, start, &DetailCustom{
+		Invoke:   call.invoke,
+		Event:    call.scenario,
+		Protocol: "http." + call.method,
+		Type:     kind,
+		RawData:  string(raw),
+		Data:     data,
+		ResTime:  strconv.FormatInt(time.Since(start).Milliseconds(), 10) + "ms",
+	})
+}
+
+// backoff is the delay before the retry following attempt, the Retry-After
+// of res when it has one.
+func (c *HTTPClient) backoff(attempt int, res *HTTPResponse) time.Duration {
+	if res != nil {
+		if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, c.cfg.MaxBackoff)
+		}
+	}
+
+	d := min(c.cfg.Backoff<<attempt, c.cfg.MaxBackoff)
+	// jitter so that the callers of a failing service do not retry together
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func retryable(ctx context.Context, res *HTTPResponse, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ErrUnavailable) {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	return res.Status == http.StatusTooManyRequests || res.Status >= 500
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func requestURL(req HTTPRequest) (string, error) {
+	target := req.URL
+	for name, value := range req.Params {
+		target = strings.ReplaceAll(target, "{"+name+"}", url.PathEscape(value))
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	if len(req.Query) > 0 {
+		query := u.Query()
+		for name, value := range req.Query {
+			query.Set(name, value)
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String(), nil
+}
+
+func requestBody(body interface{}) ([]byte, error) {
+	switch v := body.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+	return json.Marshal(body)
+}
+
+// rawBody logs a json body as json, anything else as a string.
+func rawBody(body []byte) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		return json.RawMessage(body)
+	}
+	return string(body)
+}
+
+// session is the session of the incoming request, a new one when it has none.
+func session(ctx IContext) string {
+	for _, name := range []string{"X-Session", "Session-Id"} {
+		if value := ctx.Header(name); value != "" {
+			return value
+		}
+	}
+	return uuid.NewString()
+}
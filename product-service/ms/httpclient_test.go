@@ -0,0 +1,120 @@
+package ms
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPClientRetry(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"path":    r.URL.Path,
+			"limit":   r.URL.Query().Get("limit"),
+			"session": r.Header.Get("X-Session"),
+			"auth":    r.Header.Get("Authorization"),
+		})
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	client := NewHTTPClient(HTTPClientConfig{Backoff: time.Millisecond, Log: &logs, AppName: "test"})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Session", "s1")
+	r.Header.Set("Authorization", "Bearer secret")
+	ctx := newMuxContext(httptest.NewRecorder(), r, nil, nil)
+
+	res, err := client.Do(ctx, HTTPRequest{
+		URL:     server.URL + "/products/{id}",
+		Params:  map[string]string{"id": "a b"},
+		Query:   map[string]string{"limit": "5"},
+		Service: "products",
+		Command: "get",
+	})
+	assert.NoError(t, err)
+	assert.True(t, res.OK())
+	assert.EqualValues(t, 2, calls.Load())
+
+	var body map[string]string
+	assert.NoError(t, res.Decode(&body))
+	assert.Equal(t, map[string]string{"path": "/products/a b", "limit": "5", "session": "s1", "auth": ""}, body)
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	assert.Len(t, lines, 4)
+	var line DetailLog
+	assert.NoError(t, json.Unmarshal([]byte(lines[3]), &line))
+	assert.Equal(t, "s1", line.Session)
+	assert.Equal(t, "test", line.AppName)
+	assert.Equal(t, "products.get", line.Custom.Event)
+	assert.Equal(t, "Response", line.Custom.Type)
+	assert.Equal(t, "http.GET", line.Custom.Protocol)
+
+	// a POST is not sent twice
+	calls.Store(0)
+	res, err = client.Do(ctx, HTTPRequest{Method: http.MethodPost, URL: server.URL, Body: map[string]string{"name": "p1"}})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, res.Status)
+	assert.EqualValues(t, 1, calls.Load())
+}
+
+func TestHTTPClientDoAll(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(HTTPClientConfig{Retries: -1, Log: &bytes.Buffer{}})
+	ctx := newMuxContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil, nil)
+
+	responses, err := client.DoAll(ctx,
+		HTTPRequest{URL: server.URL + "/a"},
+		HTTPRequest{URL: server.URL + "/slow", Timeout: 10 * time.Millisecond},
+		HTTPRequest{URL: server.URL + "/b"},
+	)
+	assert.Error(t, err)
+	assert.Len(t, responses, 3)
+	assert.Equal(t, "/a", string(responses[0].Body))
+	assert.Error(t, responses[1].Err)
+	assert.Equal(t, "/b", string(responses[2].Body))
+}
+
+func TestHTTPClientRedact(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "cookie-secret"})
+		w.Header().Set("X-Request-Id", "r1")
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	client := NewHTTPClient(HTTPClientConfig{Log: &logs})
+	ctx := newMuxContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil, nil)
+
+	_, err := client.Do(ctx, HTTPRequest{URL: server.URL, Headers: map[string]string{
+		"Authorization": "Bearer token-secret",
+		"X-API-Key":     "key-secret",
+		"Accept":        "application/json",
+	}})
+	assert.NoError(t, err)
+
+	for _, secret := range []string{"token-secret", "key-secret", "cookie-secret"} {
+		assert.NotContains(t, logs.String(), secret)
+	}
+	assert.Contains(t, logs.String(), "application/json")
+	assert.Contains(t, logs.String(), "r1")
+}
@@ -0,0 +1,149 @@
+package mstest
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/sing3demons/product-service/ms"
+	"github.com/stretchr/testify/assert"
+)
+
+// Broker is an ms.Broker that records published messages and delivers
+// synthetic ones to a handler with Deliver.
+type Broker struct {
+	mu        sync.Mutex
+	published []*ms.Message
+	acked     []*ms.Message
+	nacked    []*ms.Message
+}
+
+var _ ms.Broker = (*Broker)(nil)
+
+func NewBroker() *Broker {
+	return &Broker{}
+}
+
+// NewMessage builds a message of topic, value is sent as is when it is a
+// string or []byte, otherwise as json.
+func NewMessage(topic string, value interface{}, headers map[string]string) *ms.Message {
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			panic(err)
+		}
+		data = b
+	}
+	return &ms.Message{Topic: topic, Value: data, Headers: headers, Timestamp: time.Now()}
+}
+
+// Deliver runs h behind middlewares for msg as a consumer would. The messages
+// h sends are recorded on b, the result acks or nacks msg.
+func (b *Broker) Deliver(h ms.ServiceHandleFunc, msg *ms.Message, middlewares ...ms.Middleware) error {
+	handler := ms.HandleFunc(h)
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	err := handler(ms.NewConsumerContext(b, msg))
+	if err != nil {
+		b.Nack(context.Background(), msg)
+	} else {
+		b.Ack(context.Background(), msg)
+	}
+	return err
+}
+
+func (b *Broker) Publish(ctx context.Context, msg *ms.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.published = append(b.published, msg)
+	return nil
+}
+
+// Subscribe delivers nothing, use Deliver.
+func (b *Broker) Subscribe(ctx context.Context, topic, group string, handler func(ctx context.Context, msg *ms.Message)) error {
+	<-ctx.Done()
+	return nil
+}
+
+func (b *Broker) Ack(ctx context.Context, msg *ms.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.acked = append(b.acked, msg)
+	return nil
+}
+
+func (b *Broker) Nack(ctx context.Context, msg *ms.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nacked = append(b.nacked, msg)
+	return nil
+}
+
+func (b *Broker) Close() error {
+	return nil
+}
+
+// Published returns the messages published on topic, every topic when empty.
+func (b *Broker) Published(topic string) []*ms.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var messages []*ms.Message
+	for _, msg := range b.published {
+		if topic == "" || msg.Topic == topic {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+func (b *Broker) Acked() []*ms.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*ms.Message(nil), b.acked...)
+}
+
+func (b *Broker) Nacked() []*ms.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*ms.Message(nil), b.nacked...)
+}
+
+// AssertPublished asserts that a message equal to want once json encoded was
+// published on topic.
+func (b *Broker) AssertPublished(t assert.TestingT, topic string, want interface{}) bool {
+	expected, err := json.Marshal(want)
+	if !assert.NoError(t, err) {
+		return false
+	}
+
+	messages := b.Published(topic)
+	for _, msg := range messages {
+		if assert.ObjectsAreEqual(normalize(expected), normalize(msg.Value)) {
+			return true
+		}
+	}
+
+	values := make([]string, len(messages))
+	for i, msg := range messages {
+		values[i] = string(msg.Value)
+	}
+	return assert.Fail(t, "message not published", "topic: %s\nwant: %s\npublished: %v", topic, expected, values)
+}
+
+func normalize(data []byte) interface{} {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return string(data)
+	}
+	return v
+}
@@ -0,0 +1,265 @@
+// Package mstest provides fakes and helpers to test ms handlers and consumers
+// without a server or a broker.
+package mstest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sing3demons/product-service/ms"
+)
+
+// Response is a recorded call of IContext.Response.
+type Response struct {
+	Status int
+	Data   interface{}
+}
+
+// Decode converts the recorded data into v through json, e.g. to read a
+// service.Response back.
+func (r Response) Decode(v interface{}) error {
+	data, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// SentMessage is a recorded call of IContext.SendMessage.
+type SentMessage struct {
+	Topic   string
+	Message interface{}
+}
+
+// Context is a fake ms.IContext. Set the request fields before calling the
+// handler, then assert on the recorded Responses, Messages and Events.
+type Context struct {
+	Ctx     context.Context
+	Params  map[string]string
+	Queries map[string]string
+	Headers map[string]string
+	// Body is read by ReadInput, as is when it is a string or []byte,
+	// otherwise as its json encoding.
+	Body       interface{}
+	HTTPMethod string
+	RemoteIP   string
+	Values     map[string]interface{}
+
+	Responses       []Response
+	Messages        []SentMessage
+	Events          []ms.SSEEvent
+	Logs            []string
+	ResponseHeaders map[string]string
+
+	// SendError is returned by SendMessage, e.g. to test a broker failure.
+	SendError error
+
+	// HTTP answers Call and CallAll, with a 200 without body when nil. The
+	// requests are recorded in Calls.
+	HTTP  func(req ms.HTTPRequest) (*ms.HTTPResponse, error)
+	Calls []ms.HTTPRequest
+}
+
+var _ ms.IContext = (*Context)(nil)
+
+type Option func(c *Context)
+
+func WithParam(name, value string) Option {
+	return func(c *Context) { c.Params[name] = value }
+}
+
+func WithQuery(name, value string) Option {
+	return func(c *Context) { c.Queries[name] = value }
+}
+
+func WithHeader(name, value string) Option {
+	return func(c *Context) { c.Headers[http.CanonicalHeaderKey(name)] = value }
+}
+
+func WithBody(body interface{}) Option {
+	return func(c *Context) { c.Body = body }
+}
+
+func WithMethod(method string) Option {
+	return func(c *Context) { c.HTTPMethod = method }
+}
+
+// WithClaims authenticates the request as if the JWT middleware accepted it.
+func WithClaims(claims *ms.Claims) Option {
+	return func(c *Context) { c.Values[claimsKey] = claims }
+}
+
+// WithHTTP answers the outbound calls of the handler with fn.
+func WithHTTP(fn func(req ms.HTTPRequest) (*ms.HTTPResponse, error)) Option {
+	return func(c *Context) { c.HTTP = fn }
+}
+
+func WithContext(ctx context.Context) Option {
+	return func(c *Context) { c.Ctx = ctx }
+}
+
+// claimsKey is where the JWT middleware keeps the claims.
+const claimsKey = "ms.claims"
+
+func NewContext(opts ...Option) *Context {
+	c := &Context{
+		Ctx:             context.Background(),
+		Params:          map[string]string{},
+		Queries:         map[string]string{},
+		Headers:         map[string]string{},
+		Values:          map[string]interface{}{},
+		ResponseHeaders: map[string]string{},
+		HTTPMethod:      http.MethodGet,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// LastResponse is the last recorded response, the zero Response when none.
+func (c *Context) LastResponse() Response {
+	if len(c.Responses) == 0 {
+		return Response{}
+	}
+	return c.Responses[len(c.Responses)-1]
+}
+
+func (c *Context) Context() context.Context {
+	return c.Ctx
+}
+
+func (c *Context) SetContext(ctx context.Context) {
+	c.Ctx = ctx
+}
+
+func (c *Context) Log(message string) {
+	c.Logs = append(c.Logs, message)
+}
+
+func (c *Context) Param(name string) string {
+	return c.Params[name]
+}
+
+func (c *Context) Query(name string) string {
+	return c.Queries[name]
+}
+
+func (c *Context) Method() string {
+	return c.HTTPMethod
+}
+
+func (c *Context) Header(name string) string {
+	return c.Headers[http.CanonicalHeaderKey(name)]
+}
+
+func (c *Context) SetHeader(name, value string) {
+	c.ResponseHeaders[http.CanonicalHeaderKey(name)] = value
+}
+
+func (c *Context) ClientIP() string {
+	return c.RemoteIP
+}
+
+func (c *Context) Set(key string, value interface{}) {
+	c.Values[key] = value
+}
+
+func (c *Context) Get(key string) interface{} {
+	return c.Values[key]
+}
+
+func (c *Context) Claims() *ms.Claims {
+	claims, _ := c.Values[claimsKey].(*ms.Claims)
+	return claims
+}
+
+// ReadInput decodes Body and validates it like the ms contexts.
+func (c *Context) ReadInput(data interface{}) error {
+	if err := c.ReadBody(data); err != nil {
+		return err
+	}
+	return ms.Validate(data)
+}
+
+// ReadBody decodes Body without validating it, it returns io.EOF without a body.
+func (c *Context) ReadBody(data interface{}) error {
+	var body []byte
+	switch v := c.Body.(type) {
+	case nil:
+		return io.EOF
+	case string:
+		body = []byte(v)
+	case []byte:
+		body = v
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		body = b
+	}
+
+	if strings.TrimSpace(string(body)) == "" {
+		return io.EOF
+	}
+	return json.Unmarshal(body, data)
+}
+
+func (c *Context) Response(responseCode int, responseData interface{}) error {
+	c.Responses = append(c.Responses, Response{Status: responseCode, Data: responseData})
+	return nil
+}
+
+// Stream records the events fn sends, Done is closed with Ctx.
+func (c *Context) Stream(fn func(w ms.SSEWriter) error) error {
+	return fn(&sseRecorder{c: c})
+}
+
+func (c *Context) SendMessage(topic string, message interface{}, opts ...ms.OptionProducerMessage) error {
+	if c.SendError != nil {
+		return c.SendError
+	}
+	c.Messages = append(c.Messages, SentMessage{Topic: topic, Message: message})
+	return nil
+}
+
+func (c *Context) Call(req ms.HTTPRequest) (*ms.HTTPResponse, error) {
+	c.Calls = append(c.Calls, req)
+	if c.HTTP == nil {
+		return &ms.HTTPResponse{Status: http.StatusOK}, nil
+	}
+	return c.HTTP(req)
+}
+
+// CallAll answers reqs one after the other, in order.
+func (c *Context) CallAll(reqs ...ms.HTTPRequest) ([]*ms.HTTPResponse, error) {
+	responses := make([]*ms.HTTPResponse, len(reqs))
+	var errs []error
+	for i, req := range reqs {
+		res, err := c.Call(req)
+		if err != nil {
+			res = &ms.HTTPResponse{Err: err}
+			errs = append(errs, err)
+		}
+		responses[i] = res
+	}
+	return responses, errors.Join(errs...)
+}
+
+type sseRecorder struct {
+	c *Context
+}
+
+func (w *sseRecorder) Send(event ms.SSEEvent) error {
+	w.c.Events = append(w.c.Events, event)
+	return nil
+}
+
+func (w *sseRecorder) Done() <-chan struct{} {
+	return w.c.Ctx.Done()
+}
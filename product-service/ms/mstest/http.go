@@ -0,0 +1,58 @@
+package mstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/sing3demons/product-service/ms"
+)
+
+// NewRequest builds a request for Do. body is sent as is when it is a string,
+// []byte or io.Reader, otherwise as json.
+func NewRequest(method, target string, body interface{}) *http.Request {
+	var reader io.Reader
+	isJSON := false
+	switch v := body.(type) {
+	case nil:
+	case string:
+		reader = bytes.NewBufferString(v)
+	case []byte:
+		reader = bytes.NewReader(v)
+	case io.Reader:
+		reader = v
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			panic(err)
+		}
+		reader = bytes.NewReader(data)
+		isJSON = true
+	}
+
+	req := httptest.NewRequest(method, target, reader)
+	if isJSON {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req
+}
+
+// Do serves req with the routes and middlewares of app.
+func Do(app ms.IApplication, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	app.Handler().ServeHTTP(w, req)
+	return w
+}
+
+// DecodeJSON decodes the body of a recorded response into v.
+func DecodeJSON(w *httptest.ResponseRecorder, v interface{}) error {
+	return json.Unmarshal(w.Body.Bytes(), v)
+}
+
+// NewServer starts app on a local port, for clients that need a real
+// connection, e.g. streams and websockets. Close it when done.
+func NewServer(app ms.IApplication) *httptest.Server {
+	return httptest.NewServer(app.Handler())
+}
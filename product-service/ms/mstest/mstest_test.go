@@ -0,0 +1,72 @@
+package mstest
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/sing3demons/product-service/ms"
+	"github.com/stretchr/testify/assert"
+)
+
+type createProduct struct {
+	ID   string `json:"id,omitempty" path:"id"`
+	Name string `json:"name" validate:"required"`
+}
+
+var create = ms.Handle(func(ctx ms.IContext, req createProduct) (createProduct, error) {
+	if err := ctx.SendMessage("product.created", req); err != nil {
+		return createProduct{}, err
+	}
+	return req, nil
+})
+
+func TestContext(t *testing.T) {
+	ctx := NewContext(WithParam("id", "1"), WithBody(`{"name":"p1"}`))
+	assert.NoError(t, create(ctx))
+
+	res := ctx.LastResponse()
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.Equal(t, createProduct{ID: "1", Name: "p1"}, res.Data)
+	assert.Equal(t, []SentMessage{{Topic: "product.created", Message: createProduct{ID: "1", Name: "p1"}}}, ctx.Messages)
+
+	ctx = NewContext(WithBody(map[string]string{}))
+	assert.NoError(t, create(ctx))
+	assert.Equal(t, http.StatusBadRequest, ctx.LastResponse().Status)
+}
+
+func TestDo(t *testing.T) {
+	app := ms.NewApplication(ms.Config{AppConfig: ms.AppConfig{Router: ms.Mux}, Broker: ms.MemoryBroker})
+	app.Post("/products/{id}", create)
+
+	w := Do(app, NewRequest(http.MethodPost, "/products/1", map[string]string{"name": "p1"}))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var res createProduct
+	assert.NoError(t, DecodeJSON(w, &res))
+	assert.Equal(t, "1", res.ID)
+}
+
+func TestBrokerDeliver(t *testing.T) {
+	broker := NewBroker()
+	forward := func(ctx ms.IContext) error {
+		var product createProduct
+		if err := ctx.ReadInput(&product); err != nil {
+			return err
+		}
+		if ctx.Header("X-Source") != "test" {
+			return errors.New("unknown source")
+		}
+		return ctx.SendMessage("product.indexed", product)
+	}
+
+	err := broker.Deliver(forward, NewMessage("product.created", createProduct{Name: "p1"}, map[string]string{"x-source": "test"}))
+	assert.NoError(t, err)
+	broker.AssertPublished(t, "product.indexed", map[string]string{"name": "p1"})
+	assert.Len(t, broker.Acked(), 1)
+
+	err = broker.Deliver(forward, NewMessage("product.created", `{"name":"p2"}`, nil))
+	assert.Error(t, err)
+	assert.Len(t, broker.Nacked(), 1)
+	assert.Len(t, broker.Published(""), 1)
+}
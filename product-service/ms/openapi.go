@@ -0,0 +1,592 @@
+package ms
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+type OpenAPIConfig struct {
+	Enabled     bool
+	Title       string
+	Version     string
+	Description string
+	SpecPath    string // default /openapi.json
+	DocsPath    string // default /docs
+}
+
+type OpenAPI struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       OpenAPIInfo         `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components OpenAPIComponents   `json:"components,omitempty"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// PathItem maps a lower case http method to its operation.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Summary     string                     `json:"summary,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Parameters  []Parameter                `json:"parameters,omitempty"`
+	RequestBody *RequestBody               `json:"requestBody,omitempty"`
+	Responses   map[string]OpenAPIResponse `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema,omitempty"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     bool               `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+// RouteDoc annotates a route for the generated OpenAPI document. Request and
+// Response are sample values (e.g. model.Product{}) used for their type only;
+// they override the types inferred from a typed Handle.
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Request     interface{}
+	Response    interface{}
+	Status      int
+}
+
+// Describe attaches a RouteDoc to handler, which may be a plain HandleFunc or one built by Handle.
+func Describe(doc RouteDoc, handler HandleFunc) HandleFunc {
+	described := func(ctx IContext) error {
+		return handler(ctx)
+	}
+	d := handlerDocOf(handler)
+	d.doc = &doc
+	describe(described, d)
+	return described
+}
+
+// handlerDoc is what Handle and Describe tell of a handler, the document
+// is built from it without running the handlers.
+type handlerDoc struct {
+	req reflect.Type
+	res reflect.Type
+	doc *RouteDoc
+}
+
+// handlerDocs maps the handlers built by Handle and Describe to their
+// handlerDoc, the other handlers are documented without schemas.
+var handlerDocs sync.Map
+
+// handlerKey identifies handler: a func value points to its closure, which
+// Handle and Describe allocate once per handler.
+func handlerKey(handler HandleFunc) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&handler))
+}
+
+func describe(handler HandleFunc, d handlerDoc) {
+	handlerDocs.Store(handlerKey(handler), d)
+}
+
+func handlerDocOf(handler HandleFunc) handlerDoc {
+	d, _ := handlerDocs.Load(handlerKey(handler))
+	doc, _ := d.(handlerDoc)
+	return doc
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// BuildOpenAPI generates an OpenAPI 3 document from the registered routes.
+func BuildOpenAPI(cfg OpenAPIConfig, routes []Route) *OpenAPI {
+	doc := &OpenAPI{
+		OpenAPI: "3.0.3",
+		Info: OpenAPIInfo{
+			Title:       cfg.Title,
+			Version:     cfg.Version,
+			Description: cfg.Description,
+		},
+		Paths: map[string]PathItem{},
+	}
+	if doc.Info.Title == "" {
+		doc.Info.Title = "API Documentation"
+	}
+	if doc.Info.Version == "" {
+		doc.Info.Version = "1.0.0"
+	}
+
+	schemas := &schemaBuilder{components: map[string]*Schema{}}
+	for _, route := range routes {
+		path := strings.ReplaceAll(route.Path, "...}", "}")
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = PathItem{}
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = schemas.operation(route.Method, path, handlerDocOf(route.Handler))
+	}
+
+	if len(schemas.components) > 0 {
+		doc.Components.Schemas = schemas.components
+	}
+	return doc
+}
+
+func (b *schemaBuilder) operation(method, path string, d handlerDoc) *Operation {
+	op := &Operation{Responses: map[string]OpenAPIResponse{}}
+
+	req, res, status := d.req, d.res, http.StatusOK
+	if d.doc != nil {
+		op.Summary = d.doc.Summary
+		op.Description = d.doc.Description
+		op.Tags = d.doc.Tags
+		if d.doc.Request != nil {
+			req = reflect.TypeOf(d.doc.Request)
+		}
+		if d.doc.Response != nil {
+			res = reflect.TypeOf(d.doc.Response)
+		}
+		if d.doc.Status != 0 {
+			status = d.doc.Status
+		}
+	}
+
+	declared := map[string]bool{}
+	if req != nil {
+		for _, p := range b.parameters(req) {
+			declared[p.In+":"+p.Name] = true
+			op.Parameters = append(op.Parameters, p)
+		}
+
+		if method != http.MethodGet && method != http.MethodDelete {
+			if body := b.bodySchema(req); body != nil {
+				op.RequestBody = &RequestBody{
+					Required: true,
+					Content:  map[string]MediaType{"application/json": {Schema: body}},
+				}
+			}
+		}
+	}
+
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		if !declared["path:"+match[1]] {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:     match[1],
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+	}
+
+	success := OpenAPIResponse{Description: http.StatusText(status)}
+	if res != nil {
+		success.Content = map[string]MediaType{"application/json": {Schema: b.schemaOf(res)}}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	if req != nil {
+		op.Responses["400"] = OpenAPIResponse{
+			Description: http.StatusText(http.StatusBadRequest),
+			Content:     map[string]MediaType{"application/json": {Schema: b.schemaOf(reflect.TypeOf(ErrorResponse{}))}},
+		}
+	}
+
+	return op
+}
+
+type schemaBuilder struct {
+	components map[string]*Schema
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (b *schemaBuilder) parameters(t reflect.Type) []Parameter {
+	t = indirectType(t)
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var params []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && indirectType(field.Type).Kind() == reflect.Struct {
+			params = append(params, b.parameters(field.Type)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		in, name := "path", field.Tag.Get("path")
+		if name == "" {
+			in, name = "query", field.Tag.Get("query")
+		}
+		if name == "" {
+			continue
+		}
+
+		schema := b.schemaOf(field.Type)
+		applyValidateTag(schema, field.Tag.Get("validate"))
+		params = append(params, Parameter{
+			Name:     name,
+			In:       in,
+			Required: in == "path" || hasRule(field.Tag.Get("validate"), "required"),
+			Schema:   schema,
+		})
+	}
+	return params
+}
+
+// bodySchema describes the json fields of a request type, leaving out
+// the fields bound from path and query.
+func (b *schemaBuilder) bodySchema(t reflect.Type) *Schema {
+	t = indirectType(t)
+	if t.Kind() != reflect.Struct || t == timeType {
+		return b.schemaOf(t)
+	}
+
+	if !hasBoundFields(t) {
+		if schema := b.schemaOf(t); len(b.resolve(schema).Properties) > 0 {
+			return schema
+		}
+		return nil
+	}
+
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	b.structFields(t, schema, true)
+	if len(schema.Properties) == 0 {
+		return nil
+	}
+	return schema
+}
+
+func (b *schemaBuilder) resolve(schema *Schema) *Schema {
+	if schema.Ref != "" {
+		return b.components[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	return schema
+}
+
+func (b *schemaBuilder) schemaOf(t reflect.Type) *Schema {
+	t = indirectType(t)
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+
+		name := schemaName(t)
+		if name == "" {
+			schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+			b.structFields(t, schema, false)
+			return schema
+		}
+
+		if _, ok := b.components[name]; !ok {
+			schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+			b.components[name] = schema
+			b.structFields(t, schema, false)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		return &Schema{}
+	}
+}
+
+func (b *schemaBuilder) structFields(t reflect.Type, schema *Schema, skipBound bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := jsonName(field)
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" && indirectType(field.Type).Kind() == reflect.Struct {
+			b.structFields(indirectType(field.Type), schema, skipBound)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if skipBound && (field.Tag.Get("path") != "" || field.Tag.Get("query") != "") {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		rules := field.Tag.Get("validate")
+		prop := b.schemaOf(field.Type)
+		if prop.Ref == "" {
+			applyValidateTag(prop, rules)
+		}
+		schema.Properties[name] = prop
+
+		if hasRule(rules, "required") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	sort.Strings(schema.Required)
+}
+
+func hasBoundFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("path") != "" || field.Tag.Get("query") != "" {
+			return true
+		}
+		if field.Anonymous && indirectType(field.Type).Kind() == reflect.Struct && hasBoundFields(indirectType(field.Type)) {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonName(field reflect.StructField) string {
+	return strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+}
+
+func hasRule(rules, name string) bool {
+	for _, rule := range strings.Split(rules, ",") {
+		if rule == name {
+			return true
+		}
+	}
+	return false
+}
+
+// applyValidateTag maps the `validate` rules understood by ReadInput to schema keywords.
+func applyValidateTag(schema *Schema, rules string) {
+	if rules == "" {
+		return
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		if rule == "dive" {
+			return
+		}
+
+		key, param, _ := strings.Cut(rule, "=")
+		switch key {
+		case "email":
+			schema.Format = "email"
+		case "uuid", "uuid4":
+			schema.Format = "uuid"
+		case "url", "uri":
+			schema.Format = "uri"
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, v)
+			}
+		case "gt", "gte", "min", "lt", "lte", "max", "len":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			applyBound(schema, key, n)
+		}
+	}
+}
+
+func applyBound(schema *Schema, key string, n float64) {
+	lower := key == "gt" || key == "gte" || key == "min" || key == "len"
+	upper := key == "lt" || key == "lte" || key == "max" || key == "len"
+	size := int(n)
+
+	switch schema.Type {
+	case "string":
+		if lower {
+			schema.MinLength = &size
+		}
+		if upper {
+			schema.MaxLength = &size
+		}
+	case "array":
+		if lower {
+			schema.MinItems = &size
+		}
+		if upper {
+			schema.MaxItems = &size
+		}
+	case "integer", "number":
+		if lower {
+			schema.Minimum = &n
+			schema.ExclusiveMinimum = key == "gt"
+		}
+		if upper {
+			schema.Maximum = &n
+			schema.ExclusiveMaximum = key == "lt"
+		}
+	}
+}
+
+var schemaNameReplacer = strings.NewReplacer("[", "_", "]", "", "/", "_", "*", "", " ", "")
+
+// schemaName names the component of a struct type, e.g. model.Product -> Product.
+// Generic instantiations keep their type arguments, e.g. Result[model.Product] -> Result_Product.
+func schemaName(t reflect.Type) string {
+	name := t.Name()
+	if name == "" {
+		return ""
+	}
+	if i := strings.Index(name, "["); i >= 0 {
+		args := name[i+1 : len(name)-1]
+		var short []string
+		for _, arg := range strings.Split(args, ",") {
+			short = append(short, arg[strings.LastIndex(arg, ".")+1:])
+		}
+		name = name[:i] + "[" + strings.Join(short, "_") + "]"
+	}
+	return schemaNameReplacer.Replace(name)
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+//go:embed openapi.html
+var docsPage string
+
+// swaggerUI is the vendored swagger-ui, served under the docs path.
+//
+//go:embed swaggerui/swagger-ui-bundle.js swaggerui/swagger-ui.css swaggerui/favicon-32x32.png
+var swaggerUI embed.FS
+
+// docsCSP lets the docs page load its own scripts and styles only.
+const docsCSP = "default-src 'self'; img-src 'self' data:; style-src 'self' 'unsafe-inline'; frame-ancestors 'none'"
+
+// openAPIDocs serves the document of the routes of an application and its
+// docs page. The document is built once, at Start or on its first request,
+// from the routes registered by then.
+type openAPIDocs struct {
+	cfg      OpenAPIConfig
+	routes   func() []Route
+	specPath string
+	docsPath string
+	page     []byte
+	// initializer starts swagger-ui on the document, a script of its own
+	// as the CSP refuses inline ones.
+	initializer []byte
+	assets      http.Handler
+	once        sync.Once
+	spec        []byte
+}
+
+func newOpenAPIDocs(cfg OpenAPIConfig, routes func() []Route) *openAPIDocs {
+	d := &openAPIDocs{cfg: cfg, routes: routes, specPath: cfg.SpecPath, docsPath: cfg.DocsPath}
+	if d.specPath == "" {
+		d.specPath = "/openapi.json"
+	}
+	if d.docsPath == "" {
+		d.docsPath = "/docs"
+	}
+	d.page = []byte(strings.ReplaceAll(docsPage, "{{docs}}", d.docsPath))
+	spec, _ := json.Marshal(d.specPath)
+	d.initializer = []byte("window.onload = () => {\n  window.ui = SwaggerUIBundle({url: " + string(spec) + ", dom_id: '#swagger-ui'})\n}\n")
+	assets, _ := fs.Sub(swaggerUI, "swaggerui")
+	d.assets = http.StripPrefix(d.docsPath+"/", http.FileServer(http.FS(assets)))
+	return d
+}
+
+// build builds the document, once.
+func (d *openAPIDocs) build() []byte {
+	d.once.Do(func() {
+		d.spec, _ = json.Marshal(BuildOpenAPI(d.cfg, d.routes()))
+	})
+	return d.spec
+}
+
+func (d *openAPIDocs) serveSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(d.build())
+}
+
+func (d *openAPIDocs) serveDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", docsCSP)
+	w.Write(d.page)
+}
+
+// serveAsset serves the files of swagger-ui and its initializer.
+func (d *openAPIDocs) serveAsset(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimPrefix(r.URL.Path, d.docsPath+"/") == "swagger-initializer.js" {
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		w.Write(d.initializer)
+		return
+	}
+	d.assets.ServeHTTP(w, r)
+}
@@ -0,0 +1,135 @@
+package ms
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type openAPIProduct struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name" validate:"required"`
+	Price float64 `json:"price,omitempty" validate:"gt=0"`
+}
+
+type openAPIProductRequest struct {
+	ID string `path:"id"`
+	openAPIProduct
+}
+
+func TestBuildOpenAPI(t *testing.T) {
+	app := newMuxServer(Config{AppConfig: AppConfig{OpenAPI: OpenAPIConfig{Enabled: true, Title: "test"}}}).(*muxApplication)
+
+	app.Post("/products/{id}", Handle(func(ctx IContext, req openAPIProductRequest) (openAPIProduct, error) {
+		return req.openAPIProduct, nil
+	}))
+	app.Get("/products", Describe(RouteDoc{Summary: "List products", Response: []openAPIProduct{}}, func(ctx IContext) error {
+		return ctx.Response(http.StatusOK, nil)
+	}))
+	app.Get("/health/{name}", func(ctx IContext) error {
+		return ctx.Response(http.StatusOK, ctx.Param("name"))
+	})
+
+	w := httptest.NewRecorder()
+	app.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var doc OpenAPI
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&doc))
+	assert.Equal(t, "test", doc.Info.Title)
+
+	post := doc.Paths["/products/{id}"]["post"]
+	assert.Len(t, post.Parameters, 1)
+	assert.Equal(t, "path", post.Parameters[0].In)
+	body := post.RequestBody.Content["application/json"].Schema
+	assert.Contains(t, body.Properties, "name")
+	assert.NotContains(t, body.Properties, "ID")
+	assert.Equal(t, []string{"name"}, body.Required)
+	assert.Equal(t, "#/components/schemas/openAPIProduct", post.Responses["200"].Content["application/json"].Schema.Ref)
+
+	product := doc.Components.Schemas["openAPIProduct"]
+	assert.True(t, product.Properties["price"].ExclusiveMinimum)
+	assert.Equal(t, 0.0, *product.Properties["price"].Minimum)
+
+	list := doc.Paths["/products"]["get"]
+	assert.Equal(t, "List products", list.Summary)
+	assert.Equal(t, "array", list.Responses["200"].Content["application/json"].Schema.Type)
+
+	health := doc.Paths["/health/{name}"]["get"]
+	assert.Equal(t, "name", health.Parameters[0].Name)
+	assert.Nil(t, health.Responses["200"].Content)
+
+}
+
+func TestOpenAPIDocsPage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := Config{AppConfig: AppConfig{OpenAPI: OpenAPIConfig{Enabled: true}}}
+	mux := newMuxServer(cfg).(*muxApplication)
+	g := newGinServer(cfg).(*ginApplication)
+
+	for name, router := range map[string]http.Handler{"mux": mux.mux, "gin": g.router} {
+		t.Run(name, func(t *testing.T) {
+			serve := func(path string) *httptest.ResponseRecorder {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+				return w
+			}
+
+			// swagger-ui is served by the app, nothing is loaded from elsewhere
+			w := serve("/docs")
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), `src="/docs/swagger-ui-bundle.js"`)
+			assert.NotContains(t, w.Body.String(), "https://")
+			assert.Contains(t, w.Header().Get("Content-Security-Policy"), "default-src 'self'")
+
+			w = serve("/docs/swagger-ui-bundle.js")
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), "SwaggerUIBundle")
+			assert.Equal(t, http.StatusOK, serve("/docs/swagger-ui.css").Code)
+			assert.Contains(t, serve("/docs/swagger-initializer.js").Body.String(), `"/openapi.json"`)
+			assert.Equal(t, http.StatusNotFound, serve("/docs/missing.js").Code)
+		})
+	}
+}
+
+func TestOpenAPIHandlersNotRun(t *testing.T) {
+	app := newMuxServer(Config{AppConfig: AppConfig{OpenAPI: OpenAPIConfig{Enabled: true}}}).(*muxApplication)
+
+	ran := 0
+	app.Get("/panic", func(ctx IContext) error { panic("boom") })
+	app.Post("/side-effect", func(ctx IContext) error {
+		ran++
+		return ctx.Response(http.StatusOK, nil)
+	})
+	app.Put("/described", Describe(RouteDoc{Summary: "Update"}, Handle(func(ctx IContext, req openAPIProductRequest) (openAPIProduct, error) {
+		ran++
+		return req.openAPIProduct, nil
+	})))
+
+	fetch := func() OpenAPI {
+		w := httptest.NewRecorder()
+		app.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		var doc OpenAPI
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&doc))
+		return doc
+	}
+
+	doc := fetch()
+	assert.Zero(t, ran)
+	// plain handlers have no schemas
+	assert.Nil(t, doc.Paths["/side-effect"]["post"].RequestBody)
+	assert.Nil(t, doc.Paths["/panic"]["get"].Responses["200"].Content)
+	// the types of Handle are kept under Describe
+	described := doc.Paths["/described"]["put"]
+	assert.Equal(t, "Update", described.Summary)
+	assert.Equal(t, "#/components/schemas/openAPIProduct", described.Responses["200"].Content["application/json"].Schema.Ref)
+
+	// built once, the routes added later are not listed
+	app.Get("/late", func(ctx IContext) error { return nil })
+	assert.NotContains(t, fetch().Paths, "/late")
+}
@@ -0,0 +1,188 @@
+package ms
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+type OpenAPIValidatorConfig struct {
+	// SpecFile is the OpenAPI 3 document, json or yaml.
+	SpecFile string
+	// ValidateResponses checks the responses of the handlers too, answering
+	// 500 when they break the document. It is meant for development.
+	ValidateResponses bool
+}
+
+// NewOpenAPIValidator returns a Middleware that checks the path, query and
+// body of the requests against the operations of an OpenAPI document,
+// answering 400 with an ErrorResponse listing every violation, or 413 when
+// the body is over the BodyLimit. Requests of operations missing from the
+// document are let through.
+//
+//	validator, err := ms.NewOpenAPIValidator(ms.OpenAPIValidatorConfig{SpecFile: "openapi.yml"})
+//	...
+//	app.Use(validator)
+func NewOpenAPIValidator(cfg OpenAPIValidatorConfig) (Middleware, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromFile(cfg.SpecFile)
+	if err != nil {
+		return nil, fmt.Errorf("load openapi spec: %w", err)
+	}
+	if err := doc.Validate(loader.Context); err != nil {
+		return nil, fmt.Errorf("invalid openapi spec: %w", err)
+	}
+
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("openapi routes: %w", err)
+	}
+
+	options := &openapi3filter.Options{
+		MultiError: true,
+		// authentication is left to the JWT middleware
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx IContext) error {
+			hc, ok := ctx.(httpContext)
+			if !ok {
+				return next(ctx)
+			}
+
+			w, r := hc.httpRequest()
+			route, pathParams, err := router.FindRoute(r)
+			if err != nil {
+				return next(ctx)
+			}
+
+			input := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    options,
+			}
+			// the body is read here first, the BodyLimit holds for it too
+			limitBody(ctx, w, r)
+			if err := openapi3filter.ValidateRequest(ctx.Context(), input); err != nil {
+				if err := bodyError(err); errors.Is(err, ErrBodyTooLarge) {
+					return errorResponse(ctx, err)
+				}
+				return ctx.Response(http.StatusBadRequest, ErrorResponse{
+					Status:  http.StatusBadRequest,
+					Message: "request does not match the api specification",
+					Errors:  openAPIErrors("", err),
+				})
+			}
+
+			if cfg.ValidateResponses {
+				ctx = &responseValidator{IContext: ctx, input: input}
+			}
+			return next(ctx)
+		}
+	}, nil
+}
+
+// responseValidator checks the responses of a handler against the operation
+// of the request.
+type responseValidator struct {
+	IContext
+	input *openapi3filter.RequestValidationInput
+}
+
+func (c *responseValidator) Response(responseCode int, responseData interface{}) error {
+	var body []byte
+	if bodyAllowed(responseCode) {
+		data, err := json.Marshal(responseData)
+		if err != nil {
+			return err
+		}
+		body = data
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: c.input,
+		Status:                 responseCode,
+		Header:                 header,
+		Body:                   io.NopCloser(bytes.NewReader(body)),
+		Options:                c.input.Options,
+	}
+	if err := openapi3filter.ValidateResponse(c.Context(), input); err != nil {
+		fieldErrors := openAPIErrors("response", err)
+		for _, e := range fieldErrors {
+			c.Log(fmt.Sprintf("openapi %d response: %s: %s", responseCode, e.Field, e.Message))
+		}
+		return c.IContext.Response(http.StatusInternalServerError, ErrorResponse{
+			Status:  http.StatusInternalServerError,
+			Message: "response does not match the api specification",
+			Errors:  fieldErrors,
+		})
+	}
+	return c.IContext.Response(responseCode, responseData)
+}
+
+// ReadBody and httpRequest keep Handle binding and websockets working behind
+// the validator.
+func (c *responseValidator) ReadBody(data interface{}) error {
+	if reader, ok := c.IContext.(bodyReader); ok {
+		return reader.ReadBody(data)
+	}
+	return c.IContext.ReadInput(data)
+}
+
+func (c *responseValidator) httpRequest() (http.ResponseWriter, *http.Request) {
+	return c.IContext.(httpContext).httpRequest()
+}
+
+// openAPIErrors flattens the errors of openapi3filter into one FieldError per
+// violation, the field being e.g. "path.id", "query.limit" or "body.name".
+func openAPIErrors(field string, err error) []FieldError {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		var fieldErrors []FieldError
+		for _, err := range e {
+			fieldErrors = append(fieldErrors, openAPIErrors(field, err)...)
+		}
+		return fieldErrors
+	case *openapi3filter.RequestError:
+		switch {
+		case e.Parameter != nil:
+			field = e.Parameter.In + "." + e.Parameter.Name
+		case e.RequestBody != nil:
+			field = "body"
+		}
+		if e.Err == nil {
+			return []FieldError{{Field: field, Tag: "invalid", Message: e.Error()}}
+		}
+		return openAPIErrors(field, e.Err)
+	case *openapi3filter.ResponseError:
+		if e.Err == nil {
+			return []FieldError{{Field: field, Tag: "invalid", Message: e.Reason}}
+		}
+		return openAPIErrors(field, e.Err)
+	}
+
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			field = strings.TrimPrefix(field+"."+strings.Join(pointer, "."), ".")
+		}
+		return []FieldError{{Field: field, Tag: schemaErr.SchemaField, Message: schemaErr.Reason}}
+	}
+
+	if errors.Is(err, openapi3filter.ErrInvalidRequired) {
+		return []FieldError{{Field: field, Tag: "required", Message: "value is required"}}
+	}
+	return []FieldError{{Field: field, Tag: "invalid", Message: err.Error()}}
+}
@@ -0,0 +1,118 @@
+package ms
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const validatorSpec = `openapi: 3.0.3
+info:
+  title: test
+  version: "1.0"
+paths:
+  /products/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: string
+          pattern: "^[a-f0-9]{4}$"
+    put:
+      parameters:
+        - name: notify
+          in: query
+          schema:
+            type: boolean
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+                price:
+                  type: number
+                  minimum: 0
+      responses:
+        "200":
+          description: updated
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [id]
+                properties:
+                  id:
+                    type: string
+`
+
+func TestOpenAPIValidator(t *testing.T) {
+	spec := filepath.Join(t.TempDir(), "openapi.yml")
+	assert.NoError(t, os.WriteFile(spec, []byte(validatorSpec), 0o644))
+
+	validator, err := NewOpenAPIValidator(OpenAPIValidatorConfig{SpecFile: spec, ValidateResponses: true})
+	assert.NoError(t, err)
+
+	app := newMuxServer(Config{}).(*muxApplication)
+	app.Put("/products/{id}", func(ctx IContext) error {
+		var body map[string]interface{}
+		if err := ctx.ReadInput(&body); err != nil {
+			return err
+		}
+		if body["name"] == "bad response" {
+			return ctx.Response(http.StatusOK, map[string]string{"name": "x"})
+		}
+		return ctx.Response(http.StatusOK, map[string]string{"id": ctx.Param("id")})
+	}, BodyLimit(64), validator)
+	app.Get("/health", func(ctx IContext) error {
+		return ctx.Response(http.StatusOK, "ok")
+	}, validator)
+
+	do := func(method, target, body string) (int, ErrorResponse) {
+		r := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		app.mux.ServeHTTP(w, r)
+
+		var res ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return w.Code, res
+	}
+
+	code, _ := do(http.MethodPut, "/products/ab12?notify=true", `{"name":"p1","price":1}`)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, res := do(http.MethodPut, "/products/zz?notify=maybe", `{"price":-1}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	fields := map[string]string{}
+	for _, e := range res.Errors {
+		fields[e.Field] = e.Tag
+	}
+	assert.Equal(t, "pattern", fields["path.id"])
+	assert.Contains(t, fields, "query.notify")
+	assert.Equal(t, "required", fields["body.name"])
+	assert.Equal(t, "minimum", fields["body.price"])
+
+	code, res = do(http.MethodPut, "/products/ab12", `{"name":"bad response"}`)
+	assert.Equal(t, http.StatusInternalServerError, code)
+	assert.Equal(t, "required", res.Errors[0].Tag)
+
+	code, _ = do(http.MethodPut, "/products/ab12", `{"name":"`+strings.Repeat("x", 64)+`"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+
+	// not in the spec
+	code, _ = do(http.MethodGet, "/health", "")
+	assert.Equal(t, http.StatusOK, code)
+}
@@ -1,94 +1,39 @@
 package ms
 
 import (
-	"encoding/json"
-	"fmt"
-	"time"
-
 	"github.com/IBM/sarama"
 )
 
-func newProducer(brokers []string) sarama.SyncProducer {
+func newProducer(brokers []string) (sarama.SyncProducer, error) {
 	config := sarama.NewConfig()
 	config.Producer.Return.Successes = true
 	config.Producer.Return.Errors = true
 	config.Version = sarama.V2_5_0_0 // Set to Kafka version used
-	producer, err := sarama.NewSyncProducer(brokers, config)
-	if err != nil {
-		return nil
-	}
-
-	return producer
+	return sarama.NewSyncProducer(brokers, config)
 }
 
-func producer(producer sarama.SyncProducer, topic string, message interface{}, opts ...OptionProducerMessage) error {
-	timestamp := time.Now()
-
-	data, err := json.Marshal(message)
-	if err != nil {
-		return err
-	}
+func producer(producer sarama.SyncProducer, message *Message) error {
+	timestamp := message.Timestamp
 
 	msg := &sarama.ProducerMessage{
-		Topic:     topic,
-		Value:     sarama.StringEncoder(data),
+		Topic:     message.Topic,
+		Value:     sarama.ByteEncoder(message.Value),
 		Timestamp: timestamp,
 	}
 
-	if len(opts) > 0 {
-		for _, opt := range opts {
-			if opt.key != "" {
-				msg.Key = sarama.StringEncoder(opt.key)
-			}
-
-			if len(opt.headers) > 0 {
-				for _, header := range opt.headers {
-					for key, value := range header {
-						msg.Headers = append(msg.Headers, sarama.RecordHeader{
-							Key:   []byte(key),
-							Value: []byte(value),
-						})
-					}
-				}
-			}
-
-			if !opt.Timestamp.IsZero() {
-				msg.Timestamp = opt.Timestamp
-			}
-
-			if opt.Metadata != nil {
-				msg.Metadata = opt.Metadata
-			}
-
-			if opt.Offset > 0 {
-				msg.Offset = opt.Offset
-			}
-
-			if opt.Partition > 0 {
-				msg.Partition = opt.Partition
-			}
-		}
+	if message.Key != "" {
+		msg.Key = sarama.StringEncoder(message.Key)
 	}
 
-	partition, offset, err := producer.SendMessage(msg)
-	if err != nil {
-		return err
+	for key, value := range message.Headers {
+		msg.Headers = append(msg.Headers, sarama.RecordHeader{
+			Key:   []byte(key),
+			Value: []byte(value),
+		})
 	}
 
-	recordMetadata := RecordMetadata{
-		TopicName:      topic,
-		Partition:      partition,
-		Offset:         offset,
-		ErrorCode:      0,
-		Timestamp:      timestamp.String(),
-		BaseOffset:     "",
-		LogAppendTime:  "",
-		LogStartOffset: "",
-	}
-
-	fmt.Println("RecordMetadata:", recordMetadata)
-	return nil
-
+	_, _, err := producer.SendMessage(msg)
+	return err
 }
 
 type RecordMetadata struct {
@@ -0,0 +1,192 @@
+package ms
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitKeyFunc picks the client a request is counted against.
+type RateLimitKeyFunc func(ctx IContext) string
+
+// KeyByIP counts requests per client IP.
+func KeyByIP(ctx IContext) string {
+	return "ip:" + ctx.ClientIP()
+}
+
+// KeyByHeader counts requests per value of header, e.g. an API key,
+// falling back to the client IP when the header is missing.
+func KeyByHeader(name string) RateLimitKeyFunc {
+	return func(ctx IContext) string {
+		if value := ctx.Header(name); value != "" {
+			return "header:" + value
+		}
+		return KeyByIP(ctx)
+	}
+}
+
+// KeyBySubject counts requests per JWT subject, falling back to the client IP
+// for unauthenticated requests. It must run after the JWT middleware.
+func KeyBySubject(ctx IContext) string {
+	if claims := ctx.Claims(); claims != nil && claims.Subject != "" {
+		return "sub:" + claims.Subject
+	}
+	return KeyByIP(ctx)
+}
+
+// RateLimit is a token bucket refilled with Rate tokens every Period,
+// holding at most Burst tokens.
+type RateLimit struct {
+	Rate   int
+	Period time.Duration
+	Burst  int
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next token, when not allowed
+}
+
+// RateLimitStore keeps the buckets. The in-memory store is used by default,
+// a shared store (e.g. redis) keeps quotas across instances.
+type RateLimitStore interface {
+	Take(key string, limit RateLimit) (RateLimitResult, error)
+}
+
+type RateLimitConfig struct {
+	RateLimit
+	// Prefix separates the buckets of routes sharing a Store.
+	Prefix string
+	Key    RateLimitKeyFunc
+	Store  RateLimitStore
+}
+
+// NewRateLimit returns a Middleware that answers 429 once a client used its
+// quota. Each middleware keeps its own quota, so routes can be given
+// different limits:
+//
+//	app.Post("/products", h, ms.NewRateLimit(ms.RateLimitConfig{
+//		RateLimit: ms.RateLimit{Rate: 10, Period: time.Minute},
+//		Key:       ms.KeyBySubject,
+//	}))
+func NewRateLimit(cfg RateLimitConfig) Middleware {
+	if cfg.Period <= 0 {
+		cfg.Period = time.Second
+	}
+	if cfg.Rate <= 0 {
+		cfg.Rate = 1
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = cfg.Rate
+	}
+	if cfg.Key == nil {
+		cfg.Key = KeyByIP
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryRateLimitStore()
+	}
+
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx IContext) error {
+			result, err := cfg.Store.Take(cfg.Prefix+cfg.Key(ctx), cfg.RateLimit)
+			if err != nil {
+				// fail open, an unavailable store must not take the service down
+				ctx.Log("rate limit store: " + err.Error())
+				return next(ctx)
+			}
+
+			ctx.SetHeader("RateLimit-Limit", strconv.Itoa(result.Limit))
+			ctx.SetHeader("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			ctx.SetHeader("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+			if !result.Allowed {
+				ctx.SetHeader("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				return ctx.Response(http.StatusTooManyRequests, ErrorResponse{
+					Status:  http.StatusTooManyRequests,
+					Message: "too many requests",
+				})
+			}
+
+			return next(ctx)
+		}
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// fill is the time the bucket takes to refill, the routes sharing a
+	// store may have different limits.
+	fill time.Duration
+}
+
+type memoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+	sweep   time.Time
+}
+
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+func (s *memoryRateLimitStore) Take(key string, limit RateLimit) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	perToken := limit.Period / time.Duration(limit.Rate)
+	fill := time.Duration(limit.Burst) * perToken
+
+	s.evict(now, fill)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+float64(now.Sub(b.last))/float64(perToken))
+	b.last = now
+	b.fill = fill
+
+	result := RateLimitResult{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration((float64(limit.Burst) - b.tokens) * float64(perToken))
+	return result, nil
+}
+
+// evict drops the buckets that refilled completely, at most once per fill
+// period of the caller.
+func (s *memoryRateLimitStore) evict(now time.Time, fill time.Duration) {
+	if now.Sub(s.sweep) < fill {
+		return
+	}
+	s.sweep = now
+
+	for key, b := range s.buckets {
+		if now.Sub(b.last) >= b.fill {
+			delete(s.buckets, key)
+		}
+	}
+}
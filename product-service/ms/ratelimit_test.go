@@ -0,0 +1,125 @@
+package ms
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewMemoryRateLimitStore().(*memoryRateLimitStore)
+	store.now = func() time.Time { return now }
+
+	limit := RateLimit{Rate: 1, Period: time.Second, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		result, _ := store.Take("a", limit)
+		assert.True(t, result.Allowed)
+	}
+
+	result, _ := store.Take("a", limit)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+
+	result, _ = store.Take("b", limit)
+	assert.True(t, result.Allowed, "keys have their own bucket")
+
+	now = now.Add(time.Second)
+	result, _ = store.Take("a", limit)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+}
+
+func TestMemoryRateLimitStoreEvict(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewMemoryRateLimitStore().(*memoryRateLimitStore)
+	store.now = func() time.Time { return now }
+
+	hourly := RateLimit{Rate: 1, Period: time.Hour, Burst: 1}
+	result, _ := store.Take("hourly", hourly)
+	assert.True(t, result.Allowed)
+
+	// the sweep of a faster limit keeps the hourly bucket until it refilled
+	fast := RateLimit{Rate: 1, Period: time.Second, Burst: 1}
+	now = now.Add(time.Minute)
+	store.Take("fast", fast)
+	assert.Contains(t, store.buckets, "hourly")
+	result, _ = store.Take("hourly", hourly)
+	assert.False(t, result.Allowed)
+
+	now = now.Add(2 * time.Hour)
+	store.Take("fast", fast)
+	assert.NotContains(t, store.buckets, "hourly")
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	routes := func(app IApplication) {
+		app.Get("/products", func(ctx IContext) error {
+			return ctx.Response(http.StatusOK, "ok")
+		}, NewRateLimit(RateLimitConfig{
+			RateLimit: RateLimit{Rate: 1, Period: time.Minute},
+			Key:       KeyByHeader("X-API-Key"),
+		}))
+	}
+
+	mux := newMuxServer(Config{}).(*muxApplication)
+	routes(mux)
+	g := newGinServer(Config{}).(*ginApplication)
+	routes(g)
+
+	for name, router := range map[string]http.Handler{"mux": mux.mux, "gin": g.router} {
+		t.Run(name, func(t *testing.T) {
+			request := func(key string) *httptest.ResponseRecorder {
+				r := httptest.NewRequest(http.MethodGet, "/products", nil)
+				r.Header.Set("X-API-Key", key)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, r)
+				return w
+			}
+
+			w := request("k1")
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+			assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+			w = request("k1")
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+			assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+			assert.Equal(t, http.StatusOK, request("k2").Code)
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	clientIP := func(app IApplication, router http.Handler) string {
+		var ip string
+		app.Get("/ip", func(ctx IContext) error {
+			ip = ctx.ClientIP()
+			return ctx.Response(http.StatusOK, ip)
+		})
+		r := httptest.NewRequest(http.MethodGet, "/ip", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Header.Set("X-Forwarded-For", "203.0.113.7")
+		router.ServeHTTP(httptest.NewRecorder(), r)
+		return ip
+	}
+
+	mux := newMuxServer(Config{}).(*muxApplication)
+	assert.Equal(t, "10.0.0.1", clientIP(mux, mux.mux))
+	g := newGinServer(Config{}).(*ginApplication)
+	assert.Equal(t, "10.0.0.1", clientIP(g, g.router), "no proxy is trusted by default")
+	g = newGinServer(Config{AppConfig: AppConfig{TrustedProxies: []string{"10.0.0.0/8"}}}).(*ginApplication)
+	assert.Equal(t, "203.0.113.7", clientIP(g, g.router))
+
+	assert.Panics(t, func() { newGinServer(Config{AppConfig: AppConfig{TrustedProxies: []string{"proxy"}}}) })
+}
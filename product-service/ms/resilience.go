@@ -0,0 +1,393 @@
+package ms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrUnavailable matches every UnavailableError with errors.Is.
+var ErrUnavailable = errors.New("unavailable")
+
+// UnavailableError is returned without calling a dependency whose circuit
+// breaker is open or whose bulkhead is full. It answers 503.
+type UnavailableError struct {
+	Name   string
+	Reason string
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%s unavailable: %s", e.Name, e.Reason)
+}
+
+func (e *UnavailableError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+func (e *UnavailableError) StatusCode() int {
+	return http.StatusServiceUnavailable
+}
+
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerHalfOpen
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	}
+	return "closed"
+}
+
+type BreakerConfig struct {
+	// FailureThreshold consecutive failures open the breaker, default 5.
+	FailureThreshold int
+	// OpenTimeout is how long an open breaker rejects calls before letting
+	// trial calls through, default 30s.
+	OpenTimeout time.Duration
+	// HalfOpenRequests is how many trial calls run at once when half-open,
+	// and must succeed to close the breaker again, default 1.
+	HalfOpenRequests int
+	// IsFailure picks the errors that count as failures, default every error
+	// but a cancelled context and an UnavailableError.
+	IsFailure func(err error) bool
+}
+
+// CircuitBreaker stops calling a dependency after FailureThreshold
+// consecutive failures, until OpenTimeout passed and trial calls succeed.
+type CircuitBreaker struct {
+	name string
+	cfg  BreakerConfig
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int // consecutive, when closed
+	trials   int // running, when half-open
+	passed   int // succeeded, when half-open
+	openedAt time.Time
+
+	totalFailures atomic.Int64
+	rejected      atomic.Int64
+}
+
+// NewCircuitBreaker returns a closed breaker, listed by Health and Metrics
+// under name.
+func NewCircuitBreaker(name string, cfg BreakerConfig) *CircuitBreaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = isFailure
+	}
+
+	b := &CircuitBreaker{name: name, cfg: cfg}
+	resilience.addBreaker(b)
+	return b
+}
+
+func isFailure(err error) bool {
+	return err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, ErrUnavailable)
+}
+
+func (b *CircuitBreaker) Name() string {
+	return b.name
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.halfOpen()
+	return b.state
+}
+
+// Execute runs fn unless the breaker is open, then records its result. A call
+// rejected further down with an UnavailableError neither passes nor fails.
+func (b *CircuitBreaker) Execute(fn func() error) error {
+	trial, err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+	failed := b.cfg.IsFailure(err)
+	if !failed && errors.Is(err, ErrUnavailable) {
+		b.release(trial)
+		return err
+	}
+	b.record(trial, failed)
+	return err
+}
+
+// halfOpen moves an open breaker to half-open once OpenTimeout passed.
+func (b *CircuitBreaker) halfOpen() {
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cfg.OpenTimeout {
+		b.state = BreakerHalfOpen
+		b.trials = 0
+		b.passed = 0
+	}
+}
+
+func (b *CircuitBreaker) allow() (trial bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.halfOpen()
+	switch b.state {
+	case BreakerOpen:
+		b.rejected.Add(1)
+		return false, &UnavailableError{Name: b.name, Reason: "circuit breaker open"}
+	case BreakerHalfOpen:
+		if b.trials >= b.cfg.HalfOpenRequests {
+			b.rejected.Add(1)
+			return false, &UnavailableError{Name: b.name, Reason: "circuit breaker half-open"}
+		}
+		b.trials++
+		return true, nil
+	}
+	return false, nil
+}
+
+func (b *CircuitBreaker) record(trial, failed bool) {
+	if failed {
+		b.totalFailures.Add(1)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case trial && b.state == BreakerHalfOpen:
+		b.trials--
+		if failed {
+			b.open()
+			return
+		}
+		b.passed++
+		if b.passed >= b.cfg.HalfOpenRequests {
+			b.state = BreakerClosed
+			b.failures = 0
+		}
+	case b.state == BreakerClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.cfg.FailureThreshold {
+			b.open()
+		}
+	}
+}
+
+// release ends a trial call without recording a result.
+func (b *CircuitBreaker) release(trial bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if trial && b.state == BreakerHalfOpen {
+		b.trials--
+	}
+}
+
+func (b *CircuitBreaker) open() {
+	b.state = BreakerOpen
+	b.openedAt = time.Now()
+	b.failures = 0
+}
+
+type BulkheadConfig struct {
+	// MaxConcurrent calls run at once, default 10.
+	MaxConcurrent int
+	// MaxWait is how long a call waits for a slot, it is rejected at once
+	// when zero.
+	MaxWait time.Duration
+}
+
+// Bulkhead bounds the concurrent calls to a dependency, so that a slow one
+// does not hold every goroutine of the service.
+type Bulkhead struct {
+	name     string
+	maxWait  time.Duration
+	slots    chan struct{}
+	rejected atomic.Int64
+}
+
+// NewBulkhead returns a bulkhead listed by Health and Metrics under name.
+func NewBulkhead(name string, cfg BulkheadConfig) *Bulkhead {
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = 10
+	}
+
+	b := &Bulkhead{name: name, maxWait: cfg.MaxWait, slots: make(chan struct{}, cfg.MaxConcurrent)}
+	resilience.addBulkhead(b)
+	return b
+}
+
+func (b *Bulkhead) Name() string {
+	return b.name
+}
+
+// InFlight is the number of running calls.
+func (b *Bulkhead) InFlight() int {
+	return len(b.slots)
+}
+
+// Execute runs fn once a slot is free.
+func (b *Bulkhead) Execute(ctx context.Context, fn func() error) error {
+	select {
+	case b.slots <- struct{}{}:
+	default:
+		if b.maxWait <= 0 {
+			b.rejected.Add(1)
+			return &UnavailableError{Name: b.name, Reason: "bulkhead full"}
+		}
+
+		timer := time.NewTimer(b.maxWait)
+		defer timer.Stop()
+		select {
+		case b.slots <- struct{}{}:
+		case <-timer.C:
+			b.rejected.Add(1)
+			return &UnavailableError{Name: b.name, Reason: "bulkhead full"}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	defer func() { <-b.slots }()
+
+	return fn()
+}
+
+// GuardConfig puts a dependency behind a circuit breaker and a bulkhead, a
+// nil config leaves either out.
+type GuardConfig struct {
+	Breaker  *BreakerConfig
+	Bulkhead *BulkheadConfig
+}
+
+// Guard runs the calls to a dependency through its breaker and bulkhead.
+// The nil Guard runs them as is.
+type Guard struct {
+	Breaker  *CircuitBreaker
+	Bulkhead *Bulkhead
+}
+
+func NewGuard(name string, cfg GuardConfig) *Guard {
+	g := &Guard{}
+	if cfg.Breaker != nil {
+		g.Breaker = NewCircuitBreaker(name, *cfg.Breaker)
+	}
+	if cfg.Bulkhead != nil {
+		g.Bulkhead = NewBulkhead(name, *cfg.Bulkhead)
+	}
+	return g
+}
+
+// Do runs fn, failing fast with an UnavailableError when the bulkhead is full
+// or the breaker open. The error of fn is returned and recorded by the
+// breaker. The slot of the bulkhead is taken first, so that a call it rejects
+// takes no trial of a half-open breaker.
+func (g *Guard) Do(ctx context.Context, fn func() error) error {
+	if g == nil {
+		return fn()
+	}
+
+	call := fn
+	if g.Breaker != nil {
+		call = func() error { return g.Breaker.Execute(fn) }
+	}
+	if g.Bulkhead != nil {
+		return g.Bulkhead.Execute(ctx, call)
+	}
+	return call()
+}
+
+// registry lists the breakers and bulkheads for Health and Metrics, the last
+// one created wins for a name.
+type registry struct {
+	mu        sync.Mutex
+	breakers  map[string]*CircuitBreaker
+	bulkheads map[string]*Bulkhead
+}
+
+var resilience = &registry{
+	breakers:  map[string]*CircuitBreaker{},
+	bulkheads: map[string]*Bulkhead{},
+}
+
+func (r *registry) addBreaker(b *CircuitBreaker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.breakers[b.name] = b
+}
+
+func (r *registry) addBulkhead(b *Bulkhead) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bulkheads[b.name] = b
+}
+
+type BreakerStats struct {
+	Name     string `json:"name"`
+	State    string `json:"state"`
+	Failures int64  `json:"failures"`
+	Rejected int64  `json:"rejected"`
+
+	state BreakerState
+}
+
+type BulkheadStats struct {
+	Name     string `json:"name"`
+	InFlight int    `json:"in_flight"`
+	Capacity int    `json:"capacity"`
+	Rejected int64  `json:"rejected"`
+}
+
+func (r *registry) stats() ([]BreakerStats, []BulkheadStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	breakers := make([]BreakerStats, 0, len(r.breakers))
+	for _, b := range r.breakers {
+		state := b.State()
+		breakers = append(breakers, BreakerStats{
+			Name:     b.name,
+			State:    state.String(),
+			state:    state,
+			Failures: b.totalFailures.Load(),
+			Rejected: b.rejected.Load(),
+		})
+	}
+	sort.Slice(breakers, func(i, j int) bool { return breakers[i].Name < breakers[j].Name })
+
+	bulkheads := make([]BulkheadStats, 0, len(r.bulkheads))
+	for _, b := range r.bulkheads {
+		bulkheads = append(bulkheads, BulkheadStats{
+			Name:     b.name,
+			InFlight: b.InFlight(),
+			Capacity: cap(b.slots),
+			Rejected: b.rejected.Load(),
+		})
+	}
+	sort.Slice(bulkheads, func(i, j int) bool { return bulkheads[i].Name < bulkheads[j].Name })
+
+	return breakers, bulkheads
+}
@@ -0,0 +1,120 @@
+package ms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	breaker := NewCircuitBreaker("test.breaker", BreakerConfig{FailureThreshold: 2, OpenTimeout: 20 * time.Millisecond})
+	failing := func() error { return errors.New("down") }
+
+	assert.Error(t, breaker.Execute(failing))
+	assert.Equal(t, BreakerClosed, breaker.State())
+	assert.Error(t, breaker.Execute(failing))
+	assert.Equal(t, BreakerOpen, breaker.State())
+
+	calls := 0
+	err := breaker.Execute(func() error { calls++; return nil })
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, http.StatusServiceUnavailable, statusFromError(err))
+	assert.Equal(t, 0, calls)
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, BreakerHalfOpen, breaker.State())
+	// a failed trial opens it again
+	assert.Error(t, breaker.Execute(failing))
+	assert.Equal(t, BreakerOpen, breaker.State())
+
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, breaker.Execute(func() error { return nil }))
+	assert.Equal(t, BreakerClosed, breaker.State())
+}
+
+func TestBulkhead(t *testing.T) {
+	guard := NewGuard("test.bulkhead", GuardConfig{Bulkhead: &BulkheadConfig{MaxConcurrent: 1}})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go guard.Do(context.Background(), func() error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	err := guard.Do(context.Background(), func() error { return nil })
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, 1, guard.Bulkhead.InFlight())
+	close(release)
+}
+
+func TestGuardHalfOpen(t *testing.T) {
+	guard := NewGuard("test.guard", GuardConfig{
+		Breaker:  &BreakerConfig{FailureThreshold: 1, OpenTimeout: 20 * time.Millisecond, HalfOpenRequests: 2},
+		Bulkhead: &BulkheadConfig{MaxConcurrent: 1},
+	})
+	assert.Error(t, guard.Do(context.Background(), func() error { return errors.New("down") }))
+	time.Sleep(30 * time.Millisecond)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- guard.Do(context.Background(), func() error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	// the call the bulkhead rejects is no passed trial
+	assert.ErrorIs(t, guard.Do(context.Background(), func() error { return nil }), ErrUnavailable)
+	close(release)
+	assert.NoError(t, <-done)
+	assert.Equal(t, BreakerHalfOpen, guard.Breaker.State())
+
+	assert.NoError(t, guard.Do(context.Background(), func() error { return nil }))
+	assert.Equal(t, BreakerClosed, guard.Breaker.State())
+}
+
+func TestBreakerRejectedDownstream(t *testing.T) {
+	breaker := NewCircuitBreaker("test.downstream", BreakerConfig{FailureThreshold: 1, OpenTimeout: 20 * time.Millisecond})
+	assert.Error(t, breaker.Execute(func() error { return errors.New("down") }))
+	time.Sleep(30 * time.Millisecond)
+
+	// the trial is released, neither closing nor opening the breaker
+	rejected := func() error { return &UnavailableError{Name: "test.inner", Reason: "bulkhead full"} }
+	assert.ErrorIs(t, breaker.Execute(rejected), ErrUnavailable)
+	assert.Equal(t, BreakerHalfOpen, breaker.State())
+	assert.NoError(t, breaker.Execute(func() error { return nil }))
+	assert.Equal(t, BreakerClosed, breaker.State())
+}
+
+func TestHealth(t *testing.T) {
+	breaker := NewCircuitBreaker("test.health", BreakerConfig{FailureThreshold: 1})
+	breaker.Execute(func() error { return errors.New("down") })
+
+	app := newMuxServer(Config{}).(*muxApplication)
+	app.Get("/health", Health())
+	app.Get("/metrics", Metrics())
+
+	w := httptest.NewRecorder()
+	app.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var health HealthStatus
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &health))
+	assert.Equal(t, "DEGRADED", health.Status)
+	assert.Contains(t, health.Breakers, BreakerStats{Name: "test.health", State: "open", Failures: 1})
+
+	w = httptest.NewRecorder()
+	app.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, w.Body.String(), `ms_circuit_breaker_state{name="test.health"} 2`)
+}
@@ -9,25 +9,67 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+
+	"google.golang.org/grpc"
 )
 
 type muxApplication struct {
 	mux         *http.ServeMux
 	middlewares []Middleware
+	routes      []Route
+	options     map[string]HandleFunc
+	out         *output
+	sockets     *sockets
+	grpc        *grpcServer
+	broker      *lazyBroker
 	cfg         Config
+	// docs is set when OpenAPI is enabled.
+	docs *openAPIDocs
 }
 
 func newMuxServer(cfg Config) IApplication {
-	app := http.NewServeMux()
-
-	return &muxApplication{
-		mux: app,
-		cfg: cfg,
+	app := &muxApplication{
+		mux:     http.NewServeMux(),
+		options: map[string]HandleFunc{},
+		out:     newOutput(cfg.AppConfig),
+		sockets: newSockets(cfg.AppConfig.WebSocket),
+		cfg:     cfg,
+	}
+	app.broker = newLazyBroker(cfg)
+	app.grpc = newGRPCServer(cfg.AppConfig.GRPC, app.broker, app.out.client)
+
+	if cfg.AppConfig.OpenAPI.Enabled {
+		app.docs = newOpenAPIDocs(cfg.AppConfig.OpenAPI, app.Routes)
+		app.mux.HandleFunc(http.MethodGet+" "+app.docs.specPath, app.docs.serveSpec)
+		app.mux.HandleFunc(http.MethodGet+" "+app.docs.docsPath, app.docs.serveDocs)
+		app.mux.HandleFunc(http.MethodGet+" "+app.docs.docsPath+"/{file}", app.docs.serveAsset)
 	}
+
+	return app
+}
+
+func (app *muxApplication) RegisterEncoder(encoder Encoder) {
+	app.out.encoders.register(encoder)
 }
 
-func (app *muxApplication) Consume(topic string, h ServiceHandleFunc) error {
-	return consume(&app.cfg.KafkaConfig, topic, h)
+func (app *muxApplication) Handler() http.Handler {
+	return app.mux
+}
+
+func (app *muxApplication) Routes() []Route {
+	return app.routes
+}
+
+func (app *muxApplication) Consume(topic string, h ServiceHandleFunc, middlewares ...Middleware) error {
+	return consume(app.broker, app.out.client, topic, h, middlewares...)
+}
+
+func (app *muxApplication) RegisterService(desc *grpc.ServiceDesc, impl interface{}) {
+	app.grpc.register(desc, impl)
+}
+
+func (app *muxApplication) UseGRPC(middlewares ...Middleware) {
+	app.grpc.use(middlewares...)
 }
 
 func (app *muxApplication) Use(middlewares ...Middleware) {
@@ -35,19 +77,70 @@ func (app *muxApplication) Use(middlewares ...Middleware) {
 }
 
 func (app *muxApplication) Get(path string, handler HandleFunc, middlewares ...Middleware) {
-	app.mux.HandleFunc(http.MethodGet+" "+path, func(w http.ResponseWriter, r *http.Request) {
+	app.handle(http.MethodGet, path, handler, middlewares)
+}
+
+func (app *muxApplication) Post(path string, handler HandleFunc, middlewares ...Middleware) {
+	app.handle(http.MethodPost, path, handler, middlewares)
+}
+
+func (app *muxApplication) Put(path string, handler HandleFunc, middlewares ...Middleware) {
+	app.handle(http.MethodPut, path, handler, middlewares)
+}
+
+func (app *muxApplication) Patch(path string, handler HandleFunc, middlewares ...Middleware) {
+	app.handle(http.MethodPatch, path, handler, middlewares)
+}
+
+func (app *muxApplication) Delete(path string, handler HandleFunc, middlewares ...Middleware) {
+	app.handle(http.MethodDelete, path, handler, middlewares)
+}
+
+func (app *muxApplication) WebSocket(path string, handler WebSocketHandler, middlewares ...Middleware) {
+	app.handle(http.MethodGet, path, app.sockets.handler(handler), middlewares)
+}
+
+func (app *muxApplication) Options(path string, handler HandleFunc, middlewares ...Middleware) {
+	app.handle(http.MethodOptions, path, handler, middlewares)
+}
+
+func (app *muxApplication) handle(method, path string, handler HandleFunc, middlewares []Middleware) {
+	app.routes = append(app.routes, Route{Method: method, Path: path, Handler: handler})
+	app.allowOptions(path)
+
+	if method == http.MethodOptions {
+		app.options[path] = preHandle(handler, middlewares...)
+		return
+	}
+
+	app.mux.HandleFunc(method+" "+path, func(w http.ResponseWriter, r *http.Request) {
 		r = setParam(path, r)
-		preHandle(handler, preMiddleware(app.middlewares, middlewares)...)(newMuxContext(w, r, &app.cfg.KafkaConfig))
+		preHandle(handler, preMiddleware(app.middlewares, middlewares)...)(newMuxContext(w, r, app.broker, app.out))
 	})
 }
 
-func (app *muxApplication) Post(path string, handler HandleFunc, middlewares ...Middleware) {
-	app.mux.HandleFunc(http.MethodPost+" "+path, func(w http.ResponseWriter, r *http.Request) {
-		preHandle(handler, preMiddleware(app.middlewares, middlewares)...)(newMuxContext(w, r, &app.cfg.KafkaConfig))
+// allowOptions registers OPTIONS for path once, so preflight requests reach the
+// app middlewares (e.g. CORS) even when no Options handler is registered.
+func (app *muxApplication) allowOptions(path string) {
+	if _, ok := app.options[path]; ok {
+		return
+	}
+	app.options[path] = nil
+
+	app.mux.HandleFunc(http.MethodOptions+" "+path, func(w http.ResponseWriter, r *http.Request) {
+		r = setParam(path, r)
+		handler := app.options[path]
+		if handler == nil {
+			handler = optionsHandler(app.routes, path)
+		}
+		preHandle(handler, app.middlewares...)(newMuxContext(w, r, app.broker, app.out))
 	})
 }
 
 func (app *muxApplication) Start() {
+	if app.docs != nil {
+		app.docs.build()
+	}
 	server := http.Server{
 		Handler:      app.mux,
 		Addr:         ":" + app.cfg.AppConfig.Port,
@@ -55,6 +148,8 @@ func (app *muxApplication) Start() {
 		ReadTimeout:  time.Second * 10,
 	}
 
+	server.RegisterOnShutdown(app.sockets.shutdown)
+
 	shutdown := make(chan error)
 
 	go func() {
@@ -67,9 +162,16 @@ func (app *muxApplication) Start() {
 		defer cancel()
 
 		log.Printf("Shutdown server: %s\n", server.Addr)
+		app.grpc.shutdown(ctx)
 		shutdown <- server.Shutdown(ctx)
 	}()
 
+	go func() {
+		if err := app.grpc.serve(); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
 	log.Printf("Start server: %s\n", server.Addr)
 	err := server.ListenAndServe()
 	if !errors.Is(err, http.ErrServerClosed) {
@@ -10,44 +10,138 @@ import (
 	"syscall"
 
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 )
 
 func newGinServer(cfg Config) IApplication {
 	r := gin.Default()
-	return &ginApplication{router: r, cfg: cfg}
+	// gin trusts every proxy by default, so any client could pick its IP
+	if err := r.SetTrustedProxies(cfg.AppConfig.TrustedProxies); err != nil {
+		panic(fmt.Sprintf("ms: trusted proxies: %v", err))
+	}
+	app := &ginApplication{router: r, options: map[string]HandleFunc{}, out: newOutput(cfg.AppConfig), sockets: newSockets(cfg.AppConfig.WebSocket), cfg: cfg}
+	app.broker = newLazyBroker(cfg)
+	app.grpc = newGRPCServer(cfg.AppConfig.GRPC, app.broker, app.out.client)
+
+	if cfg.AppConfig.OpenAPI.Enabled {
+		app.docs = newOpenAPIDocs(cfg.AppConfig.OpenAPI, app.Routes)
+		r.GET(app.docs.specPath, gin.WrapF(app.docs.serveSpec))
+		r.GET(app.docs.docsPath, gin.WrapF(app.docs.serveDocs))
+		r.GET(app.docs.docsPath+"/:file", gin.WrapF(app.docs.serveAsset))
+	}
+
+	return app
 }
 
 type ginApplication struct {
 	router      *gin.Engine
 	middlewares []Middleware
+	routes      []Route
+	options     map[string]HandleFunc
+	out         *output
+	sockets     *sockets
+	grpc        *grpcServer
+	broker      *lazyBroker
 	cfg         Config
+	// docs is set when OpenAPI is enabled.
+	docs *openAPIDocs
+}
+
+func (app *ginApplication) RegisterEncoder(encoder Encoder) {
+	app.out.encoders.register(encoder)
 }
 
-func (app *ginApplication) Consume(topic string, h ServiceHandleFunc) error {
-	return consume(&app.cfg.KafkaConfig, topic, h)
+func (app *ginApplication) Handler() http.Handler {
+	return app.router
+}
+
+func (app *ginApplication) Routes() []Route {
+	return app.routes
+}
+
+func (app *ginApplication) Consume(topic string, h ServiceHandleFunc, middlewares ...Middleware) error {
+	return consume(app.broker, app.out.client, topic, h, middlewares...)
 }
 
 func (app *ginApplication) Get(path string, handler HandleFunc, middlewares ...Middleware) {
-	app.router.GET(path, func(c *gin.Context) {
-		preHandle(handler, preMiddleware(app.middlewares, middlewares)...)(newGinContext(c, &app.cfg.KafkaConfig))
-	})
+	app.handle(http.MethodGet, path, handler, middlewares)
 }
 
 func (app *ginApplication) Post(path string, handler HandleFunc, middlewares ...Middleware) {
-	app.router.POST(path, func(c *gin.Context) {
-		preHandle(handler, preMiddleware(app.middlewares, middlewares)...)(newGinContext(c, &app.cfg.KafkaConfig))
+	app.handle(http.MethodPost, path, handler, middlewares)
+}
+
+func (app *ginApplication) Put(path string, handler HandleFunc, middlewares ...Middleware) {
+	app.handle(http.MethodPut, path, handler, middlewares)
+}
+
+func (app *ginApplication) Patch(path string, handler HandleFunc, middlewares ...Middleware) {
+	app.handle(http.MethodPatch, path, handler, middlewares)
+}
+
+func (app *ginApplication) Delete(path string, handler HandleFunc, middlewares ...Middleware) {
+	app.handle(http.MethodDelete, path, handler, middlewares)
+}
+
+func (app *ginApplication) WebSocket(path string, handler WebSocketHandler, middlewares ...Middleware) {
+	app.handle(http.MethodGet, path, app.sockets.handler(handler), middlewares)
+}
+
+func (app *ginApplication) Options(path string, handler HandleFunc, middlewares ...Middleware) {
+	app.handle(http.MethodOptions, path, handler, middlewares)
+}
+
+func (app *ginApplication) handle(method, path string, handler HandleFunc, middlewares []Middleware) {
+	app.routes = append(app.routes, Route{Method: method, Path: path, Handler: handler})
+	app.allowOptions(path)
+
+	if method == http.MethodOptions {
+		app.options[path] = preHandle(handler, middlewares...)
+		return
+	}
+
+	app.router.Handle(method, ginPath(path), func(c *gin.Context) {
+		preHandle(handler, preMiddleware(app.middlewares, middlewares)...)(newGinContext(c, app.broker, app.out))
+	})
+}
+
+// allowOptions registers OPTIONS for path once, see muxApplication.allowOptions.
+func (app *ginApplication) allowOptions(path string) {
+	if _, ok := app.options[path]; ok {
+		return
+	}
+	app.options[path] = nil
+
+	app.router.OPTIONS(ginPath(path), func(c *gin.Context) {
+		handler := app.options[path]
+		if handler == nil {
+			handler = optionsHandler(app.routes, path)
+		}
+		preHandle(handler, app.middlewares...)(newGinContext(c, app.broker, app.out))
 	})
 }
 
+func (app *ginApplication) RegisterService(desc *grpc.ServiceDesc, impl interface{}) {
+	app.grpc.register(desc, impl)
+}
+
+func (app *ginApplication) UseGRPC(middlewares ...Middleware) {
+	app.grpc.use(middlewares...)
+}
+
 func (app *ginApplication) Use(middlewares ...Middleware) {
 	app.middlewares = append(app.middlewares, middlewares...)
 }
 
 func (app *ginApplication) Start() {
+	if app.docs != nil {
+		app.docs.build()
+	}
 	srv := http.Server{
 		Addr:    ":" + app.cfg.AppConfig.Port,
 		Handler: app.router,
 	}
+	srv.RegisterOnShutdown(app.sockets.shutdown)
 
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -55,11 +149,18 @@ func (app *ginApplication) Start() {
 		}
 	}()
 
+	go func() {
+		if err := app.grpc.serve(); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
 
 	<-shutdown
 	fmt.Println("shutting down...")
+	app.grpc.shutdown(context.Background())
 	if err := srv.Shutdown(context.Background()); err != nil {
 		fmt.Println("shutdown err:", err)
 		log.Fatal(err)
@@ -0,0 +1,194 @@
+package ms
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const bodyLimitKey = "ms.bodyLimit"
+
+// ErrBodyTooLarge is returned by ReadInput when the body exceeds the BodyLimit.
+var ErrBodyTooLarge = NewError(http.StatusRequestEntityTooLarge, "request body too large")
+
+// optionsHandler answers OPTIONS for path with the methods registered on it.
+func optionsHandler(routes []Route, path string) HandleFunc {
+	return func(ctx IContext) error {
+		methods := []string{http.MethodOptions}
+		for _, route := range routes {
+			if route.Path == path && route.Method != http.MethodOptions {
+				methods = append(methods, route.Method)
+			}
+		}
+		sort.Strings(methods)
+
+		ctx.SetHeader("Allow", strings.Join(methods, ", "))
+		return ctx.Response(http.StatusNoContent, nil)
+	}
+}
+
+type CORSConfig struct {
+	// AllowOrigins lists the allowed origins, "*" allows any origin and
+	// "https://*.example.com" any sub domain. Default "*".
+	AllowOrigins []string
+	// AllowMethods default GET, POST, PUT, PATCH, DELETE, OPTIONS.
+	AllowMethods []string
+	// AllowHeaders default Content-Type, Authorization.
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// CORS adds the CORS headers to responses and answers preflight requests.
+// Register it with app.Use so it also runs on the OPTIONS routes.
+func CORS(cfg CORSConfig) Middleware {
+	if len(cfg.AllowOrigins) == 0 {
+		cfg.AllowOrigins = []string{"*"}
+	}
+	if len(cfg.AllowMethods) == 0 {
+		cfg.AllowMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions}
+	}
+	if len(cfg.AllowHeaders) == 0 {
+		cfg.AllowHeaders = []string{"Content-Type", "Authorization"}
+	}
+
+	allowMethods := strings.Join(cfg.AllowMethods, ", ")
+	allowHeaders := strings.Join(cfg.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(cfg.ExposeHeaders, ", ")
+
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx IContext) error {
+			origin := ctx.Header("Origin")
+			if origin == "" {
+				return next(ctx)
+			}
+
+			preflight := ctx.Method() == http.MethodOptions && ctx.Header("Access-Control-Request-Method") != ""
+
+			ctx.SetHeader("Vary", "Origin")
+			if !allowOrigin(cfg.AllowOrigins, origin) {
+				if preflight {
+					return ctx.Response(http.StatusForbidden, ErrorResponse{
+						Status:  http.StatusForbidden,
+						Message: "origin not allowed",
+					})
+				}
+				return next(ctx)
+			}
+
+			if contains(cfg.AllowOrigins, "*") && !cfg.AllowCredentials {
+				ctx.SetHeader("Access-Control-Allow-Origin", "*")
+			} else {
+				ctx.SetHeader("Access-Control-Allow-Origin", origin)
+			}
+			if cfg.AllowCredentials {
+				ctx.SetHeader("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if exposeHeaders != "" {
+					ctx.SetHeader("Access-Control-Expose-Headers", exposeHeaders)
+				}
+				return next(ctx)
+			}
+
+			ctx.SetHeader("Access-Control-Allow-Methods", allowMethods)
+			ctx.SetHeader("Access-Control-Allow-Headers", allowHeaders)
+			if cfg.MaxAge > 0 {
+				ctx.SetHeader("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+			}
+			return ctx.Response(http.StatusNoContent, nil)
+		}
+	}
+}
+
+func allowOrigin(allowed []string, origin string) bool {
+	for _, o := range allowed {
+		if o == "*" || o == origin {
+			return true
+		}
+		if scheme, host, ok := strings.Cut(o, "://*."); ok && strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(origin, "."+host) {
+			return true
+		}
+	}
+	return false
+}
+
+type SecurityHeadersConfig struct {
+	// HSTSMaxAge default one year, a negative value disables HSTS.
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	// ContentSecurityPolicy default "default-src 'none'; frame-ancestors 'none'".
+	ContentSecurityPolicy string
+	// FrameOptions default DENY.
+	FrameOptions string
+	// ReferrerPolicy default no-referrer.
+	ReferrerPolicy string
+}
+
+// SecurityHeaders sets HSTS, X-Content-Type-Options, CSP and related headers.
+func SecurityHeaders(cfg SecurityHeadersConfig) Middleware {
+	if cfg.HSTSMaxAge == 0 {
+		cfg.HSTSMaxAge = 365 * 24 * time.Hour
+	}
+	if cfg.ContentSecurityPolicy == "" {
+		cfg.ContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'"
+	}
+	if cfg.FrameOptions == "" {
+		cfg.FrameOptions = "DENY"
+	}
+	if cfg.ReferrerPolicy == "" {
+		cfg.ReferrerPolicy = "no-referrer"
+	}
+
+	hsts := ""
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge.Seconds()))
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx IContext) error {
+			if hsts != "" {
+				ctx.SetHeader("Strict-Transport-Security", hsts)
+			}
+			ctx.SetHeader("X-Content-Type-Options", "nosniff")
+			ctx.SetHeader("Content-Security-Policy", cfg.ContentSecurityPolicy)
+			ctx.SetHeader("X-Frame-Options", cfg.FrameOptions)
+			ctx.SetHeader("Referrer-Policy", cfg.ReferrerPolicy)
+			return next(ctx)
+		}
+	}
+}
+
+// BodyLimit caps the body ReadInput accepts to limit bytes, larger bodies
+// fail with ErrBodyTooLarge (413).
+func BodyLimit(limit int64) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx IContext) error {
+			ctx.Set(bodyLimitKey, limit)
+			return next(ctx)
+		}
+	}
+}
+
+// limitBody wraps the request body with the BodyLimit stored on ctx.
+func limitBody(ctx IContext, w http.ResponseWriter, r *http.Request) {
+	if limit, ok := ctx.Get(bodyLimitKey).(int64); ok && limit > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+	}
+}
+
+func bodyError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return ErrBodyTooLarge
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package ms
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityMiddlewares(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	routes := func(app IApplication) {
+		app.Use(CORS(CORSConfig{AllowOrigins: []string{"https://*.example.com"}}), SecurityHeaders(SecurityHeadersConfig{}))
+		app.Get("/products", func(ctx IContext) error {
+			return ctx.Response(http.StatusOK, "ok")
+		})
+		app.Post("/products", Handle(func(ctx IContext, req handleResponse) (handleResponse, error) {
+			return req, nil
+		}), BodyLimit(16))
+	}
+
+	mux := newMuxServer(Config{}).(*muxApplication)
+	routes(mux)
+	g := newGinServer(Config{}).(*ginApplication)
+	routes(g)
+
+	for name, router := range map[string]http.Handler{"mux": mux.mux, "gin": g.router} {
+		serve := func(r *http.Request) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			return w
+		}
+
+		t.Run(name+"/Preflight", func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodOptions, "/products", nil)
+			r.Header.Set("Origin", "https://shop.example.com")
+			r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			w := serve(r)
+
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Equal(t, "https://shop.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+			assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), http.MethodPost)
+			assert.Empty(t, w.Body.String())
+		})
+
+		t.Run(name+"/PreflightOriginDenied", func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodOptions, "/products", nil)
+			r.Header.Set("Origin", "https://evil.com")
+			r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+
+			assert.Equal(t, http.StatusForbidden, serve(r).Code)
+		})
+
+		t.Run(name+"/Options", func(t *testing.T) {
+			w := serve(httptest.NewRequest(http.MethodOptions, "/products", nil))
+
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Equal(t, "GET, OPTIONS, POST", w.Header().Get("Allow"))
+		})
+
+		t.Run(name+"/Headers", func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/products", nil)
+			r.Header.Set("Origin", "https://shop.example.com")
+			w := serve(r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "https://shop.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+			assert.NotEmpty(t, w.Header().Get("Strict-Transport-Security"))
+			assert.NotEmpty(t, w.Header().Get("Content-Security-Policy"))
+		})
+
+		t.Run(name+"/BodyLimit", func(t *testing.T) {
+			w := serve(httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(`{"name":"a very long product name"}`)))
+			assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+			w = serve(httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(`{"name":"p1"}`)))
+			assert.Equal(t, http.StatusOK, w.Code)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package ms
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultHeartbeat = 15 * time.Second
+
+// ErrStreamUnsupported is returned by Stream on contexts that cannot stream, e.g. consumers.
+var ErrStreamUnsupported = errors.New("streaming not supported")
+
+// SSEEvent is one Server-Sent Event. Data is sent as is when it is a string
+// or []byte, otherwise as json.
+type SSEEvent struct {
+	ID    string
+	Event string
+	Data  interface{}
+	Retry time.Duration
+}
+
+type SSEWriter interface {
+	Send(event SSEEvent) error
+	// Done is closed when the client disconnects.
+	Done() <-chan struct{}
+}
+
+type sseWriter struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+	done    <-chan struct{}
+}
+
+func (s *sseWriter) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *sseWriter) Send(event SSEEvent) error {
+	var buf bytes.Buffer
+	if event.ID != "" {
+		fmt.Fprintf(&buf, "id: %s\n", event.ID)
+	}
+	if event.Event != "" {
+		fmt.Fprintf(&buf, "event: %s\n", event.Event)
+	}
+	if event.Retry > 0 {
+		fmt.Fprintf(&buf, "retry: %d\n", event.Retry.Milliseconds())
+	}
+
+	var data string
+	switch v := event.Data.(type) {
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&buf, "data: %s\n", line)
+	}
+	buf.WriteString("\n")
+
+	return s.write(buf.Bytes())
+}
+
+func (s *sseWriter) write(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return http.ErrAbortHandler
+	default:
+	}
+
+	if _, err := s.w.Write(b); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// stream answers r with an event stream fed by fn, sending a comment every
+// heartbeat so proxies keep the connection open. It returns when fn returns.
+func stream(w http.ResponseWriter, r *http.Request, heartbeat time.Duration, fn func(w SSEWriter) error) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return ErrStreamUnsupported
+	}
+
+	// the server WriteTimeout would cut long lived streams
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	sw := &sseWriter{w: w, flusher: flusher, done: r.Context().Done()}
+
+	if heartbeat <= 0 {
+		heartbeat = defaultHeartbeat
+	}
+
+	finished := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := sw.write([]byte(": ping " + strconv.FormatInt(time.Now().Unix(), 10) + "\n\n")); err != nil {
+					return
+				}
+			case <-sw.done:
+				return
+			case <-finished:
+				return
+			}
+		}
+	}()
+
+	err := fn(sw)
+	close(finished)
+	wg.Wait()
+
+	if errors.Is(err, http.ErrAbortHandler) {
+		// the client went away
+		return nil
+	}
+	return err
+}
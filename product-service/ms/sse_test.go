@@ -0,0 +1,63 @@
+package ms
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStream(t *testing.T) {
+	bus := NewEventBus(0)
+	done := make(chan struct{})
+
+	app := newMuxServer(Config{AppConfig: AppConfig{StreamHeartbeat: 20 * time.Millisecond}}).(*muxApplication)
+	app.Get("/stream", func(ctx IContext) error {
+		events, cancel := bus.Subscribe("products")
+		defer cancel()
+
+		err := ctx.Stream(func(w SSEWriter) error {
+			for {
+				select {
+				case <-w.Done():
+					return nil
+				case e := <-events:
+					if err := w.Send(SSEEvent{ID: "1", Event: "created", Data: e.Data}); err != nil {
+						return err
+					}
+				}
+			}
+		})
+		close(done)
+		return err
+	})
+
+	server := httptest.NewServer(app.mux)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/stream")
+	assert.NoError(t, err)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	bus.Publish("products", map[string]string{"name": "p1"})
+
+	var lines []string
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() && len(lines) < 6 {
+		lines = append(lines, scanner.Text())
+	}
+	body := strings.Join(lines, "\n")
+	assert.Contains(t, body, "id: 1\nevent: created\ndata: {\"name\":\"p1\"}\n")
+	assert.Contains(t, body, ": ping")
+
+	res.Body.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stream not stopped on disconnect")
+	}
+}
@@ -0,0 +1,22 @@
+package ms
+
+import (
+	"context"
+	"time"
+)
+
+// Timeout sets a deadline on the request context, handlers passing
+// ctx.Context() to the DataStore stop waiting once it is reached.
+//
+//	app.Get("/products", h, ms.Timeout(2*time.Second))
+func Timeout(timeout time.Duration) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx IContext) error {
+			c, cancel := context.WithTimeout(ctx.Context(), timeout)
+			defer cancel()
+
+			ctx.SetContext(c)
+			return next(ctx)
+		}
+	}
+}
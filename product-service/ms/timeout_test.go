@@ -0,0 +1,34 @@
+package ms
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	app := newMuxServer(Config{}).(*muxApplication)
+	app.Get("/slow", Handle(func(ctx IContext, req struct{}) (string, error) {
+		_, ok := ctx.Context().Deadline()
+		assert.True(t, ok)
+
+		<-ctx.Context().Done()
+		return "", ctx.Context().Err()
+	}), Timeout(10*time.Millisecond))
+
+	w := httptest.NewRecorder()
+	app.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+}
+
+func TestConsumerContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := newConsumerContext(ctx, nil, nil, &Message{Value: []byte(`{}`)})
+
+	cancel()
+	assert.ErrorIs(t, c.Context().Err(), context.Canceled)
+}
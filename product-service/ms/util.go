@@ -3,6 +3,7 @@ package ms
 import (
 	"context"
 	"net/http"
+	"net/url"
 	"strings"
 )
 
@@ -12,6 +13,36 @@ func removeBraces(str string) string {
 
 type ContextKey string
 
+// queryParam is the first value of the query parameter name of rawQuery.
+// Unlike url.ParseQuery it splits the parameters on & only, a ; is part of
+// the value, as RSQL filters need.
+func queryParam(rawQuery, name string) string {
+	for _, pair := range strings.Split(rawQuery, "&") {
+		key, value, _ := strings.Cut(pair, "=")
+		if key, err := url.QueryUnescape(key); err != nil || key != name {
+			continue
+		}
+		value, err := url.QueryUnescape(value)
+		if err != nil {
+			continue
+		}
+		return value
+	}
+	return ""
+}
+
+// ginPath converts "{id}" path params to gin's ":id" syntax so routes are
+// registered the same way on both routers.
+func ginPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			parts[i] = ":" + removeBraces(part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
 func setParam(path string, r *http.Request) *http.Request {
 	subPath := strings.Split(path, "/")
 	sss := strings.Split(r.URL.Path, "/")
@@ -65,3 +96,32 @@ func preMiddleware(app []Middleware, middlewares []Middleware) []Middleware {
 	}
 	return m
 }
+
+// When runs middlewares only for the requests match accepts, e.g. one gRPC
+// method:
+//
+//	app.UseGRPC(ms.When(func(ctx ms.IContext) bool {
+//		return ctx.Method() == productpb.ProductService_CreateProduct_FullMethodName
+//	}, auth))
+func When(match func(ctx IContext) bool, middlewares ...Middleware) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		wrapped := preHandle(next, middlewares...)
+		return func(ctx IContext) error {
+			if match(ctx) {
+				return wrapped(ctx)
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// bodyAllowed reports whether a response with status may carry a body.
+func bodyAllowed(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
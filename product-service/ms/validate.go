@@ -0,0 +1,150 @@
+package ms
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// report fields by their json name so errors match the payload the client sent
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	return v
+}
+
+// RegisterValidation adds a custom rule usable in `validate` struct tags, e.g.
+//
+//	ms.RegisterValidation("sku", func(fl validator.FieldLevel) bool {
+//		return skuPattern.MatchString(fl.Field().String())
+//	})
+func RegisterValidation(tag string, fn validator.Func) error {
+	return validate.RegisterValidation(tag, fn)
+}
+
+// Validate checks data against its validate tags like ReadInput does, for
+// inputs that are not read from a request body.
+func Validate(data interface{}) error {
+	return validateInput(data)
+}
+
+type FieldError struct {
+	Field   string      `json:"field"`
+	Tag     string      `json:"tag"`
+	Param   string      `json:"param,omitempty"`
+	Value   interface{} `json:"value,omitempty"`
+	Message string      `json:"message"`
+}
+
+// ValidationError lists every field of a payload that failed its `validate` rules.
+type ValidationError struct {
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	var fields []string
+	for _, f := range e.Errors {
+		fields = append(fields, f.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Message, strings.Join(fields, "; "))
+}
+
+// validateInput runs the `validate` struct tags of data, which may be a struct
+// or a slice of structs (optionally behind pointers).
+func validateInput(data interface{}) error {
+	val := reflect.ValueOf(data)
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+
+	var err error
+	switch val.Kind() {
+	case reflect.Struct:
+		err = validate.Struct(val.Interface())
+	case reflect.Slice, reflect.Array:
+		elem := val.Type().Elem()
+		for elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct {
+			return nil
+		}
+		err = validate.Var(val.Interface(), "dive")
+	default:
+		return nil
+	}
+
+	return newValidationError(err)
+}
+
+func newValidationError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return err
+	}
+
+	result := &ValidationError{Message: "validation failed"}
+	for _, fe := range errs {
+		field := fieldPath(fe.Namespace())
+		result.Errors = append(result.Errors, FieldError{
+			Field:   field,
+			Tag:     fe.Tag(),
+			Param:   fe.Param(),
+			Value:   fe.Value(),
+			Message: fieldMessage(field, fe),
+		})
+	}
+	return result
+}
+
+// fieldPath drops the root type name from a validator namespace,
+// "Product.name" -> "name", "[0].name" is kept as is.
+func fieldPath(namespace string) string {
+	if strings.HasPrefix(namespace, "[") {
+		return namespace
+	}
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+func fieldMessage(field string, fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "gt", "gte", "lt", "lte", "min", "max", "len", "eq", "ne":
+		return fmt.Sprintf("%s must be %s %s", field, fe.Tag(), fe.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of [%s]", field, fe.Param())
+	default:
+		if fe.Param() != "" {
+			return fmt.Sprintf("%s failed on %s=%s", field, fe.Tag(), fe.Param())
+		}
+		return fmt.Sprintf("%s failed on %s", field, fe.Tag())
+	}
+}
@@ -0,0 +1,61 @@
+package ms
+
+import (
+	"errors"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+type validateProduct struct {
+	Name  string  `json:"name" validate:"required"`
+	Price float64 `json:"price" validate:"gt=0"`
+	SKU   string  `json:"sku,omitempty" validate:"omitempty,sku"`
+}
+
+func init() {
+	skuPattern := regexp.MustCompile(`^[A-Z]{3}-\d{4}$`)
+	RegisterValidation("sku", func(fl validator.FieldLevel) bool {
+		return skuPattern.MatchString(fl.Field().String())
+	})
+}
+
+func TestReadInputValidation(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/products", strings.NewReader(`{"name":"p1","price":10,"sku":"ABC-1234"}`))
+		ctx := newMuxContext(httptest.NewRecorder(), r, nil, nil)
+
+		var product validateProduct
+		assert.NoError(t, ctx.ReadInput(&product))
+		assert.Equal(t, "p1", product.Name)
+	})
+
+	t.Run("ListsEveryField", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/products", strings.NewReader(`{"price":-1,"sku":"abc"}`))
+		ctx := newMuxContext(httptest.NewRecorder(), r, nil, nil)
+
+		var product validateProduct
+		err := ctx.ReadInput(&product)
+
+		var validationErr *ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Len(t, validationErr.Errors, 3)
+		assert.Equal(t, "name", validationErr.Errors[0].Field)
+		assert.Equal(t, "required", validationErr.Errors[0].Tag)
+		assert.Equal(t, "price", validationErr.Errors[1].Field)
+		assert.Equal(t, "sku", validationErr.Errors[2].Tag)
+	})
+
+	t.Run("Slice", func(t *testing.T) {
+		err := validateInput(&[]validateProduct{{Name: "p1", Price: 1}, {Price: 1}})
+
+		var validationErr *ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Len(t, validationErr.Errors, 1)
+		assert.Equal(t, "[1].name", validationErr.Errors[0].Field)
+	})
+}
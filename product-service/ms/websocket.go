@@ -0,0 +1,285 @@
+package ms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultPingPeriod = 30 * time.Second
+	defaultReadLimit  = 1 << 20
+	writeWait         = 10 * time.Second
+)
+
+var ErrWebSocketUnsupported = errors.New("websocket not supported")
+
+type WebSocketConfig struct {
+	// PingPeriod is how often connections are pinged, a connection without
+	// pong for two periods is closed. Default 30s.
+	PingPeriod time.Duration
+	// ReadLimit is the largest message read, default 1MB.
+	ReadLimit int64
+	// CheckOrigin accepts the upgrade requests, default same origin only.
+	CheckOrigin func(r *http.Request) bool
+}
+
+// WSContext is the context of a WebSocket connection. The IContext methods
+// read the upgrade request, Response and ReadInput write and read messages.
+type WSContext interface {
+	IContext
+	// ReadJSON reads the next message, it returns io.EOF once the client closed
+	// and a *BindError when the message is not valid json.
+	// Handlers must keep reading for pongs and close frames to be processed.
+	ReadJSON(v interface{}) error
+	WriteJSON(v interface{}) error
+	Close(code int, reason string) error
+	// Done is closed with the connection.
+	Done() <-chan struct{}
+}
+
+type WebSocketHandler func(ctx WSContext) error
+
+// httpContext is implemented by the contexts of the routers.
+type httpContext interface {
+	httpRequest() (http.ResponseWriter, *http.Request)
+}
+
+type wsContext struct {
+	IContext
+	conn   *websocket.Conn
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+func (c *wsContext) ReadJSON(v interface{}) error {
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+			return io.EOF
+		}
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return &BindError{Source: "message", Err: err}
+	}
+	return nil
+}
+
+func (c *wsContext) ReadInput(data interface{}) error {
+	if err := c.ReadJSON(data); err != nil {
+		return err
+	}
+	return validateInput(data)
+}
+
+func (c *wsContext) WriteJSON(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.conn.WriteJSON(v)
+}
+
+// Response sends responseData as a message, the code is not sent.
+func (c *wsContext) Response(responseCode int, responseData interface{}) error {
+	return c.WriteJSON(responseData)
+}
+
+func (c *wsContext) Stream(fn func(w SSEWriter) error) error {
+	return ErrStreamUnsupported
+}
+
+func (c *wsContext) Done() <-chan struct{} {
+	return c.Context().Done()
+}
+
+func (c *wsContext) Close(code int, reason string) error {
+	var err error
+	c.once.Do(func() {
+		c.cancel()
+
+		c.mu.Lock()
+		c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeWait))
+		c.mu.Unlock()
+
+		err = c.conn.Close()
+	})
+	return err
+}
+
+func (c *wsContext) ping(period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.mu.Lock()
+			err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait))
+			c.mu.Unlock()
+			if err != nil {
+				return
+			}
+		case <-c.Done():
+			return
+		}
+	}
+}
+
+// sockets upgrades the WebSocket routes of an application and closes the
+// open connections on shutdown.
+type sockets struct {
+	mu       sync.Mutex
+	conns    map[*wsContext]struct{}
+	upgrader websocket.Upgrader
+	cfg      WebSocketConfig
+}
+
+func newSockets(cfg WebSocketConfig) *sockets {
+	if cfg.PingPeriod <= 0 {
+		cfg.PingPeriod = defaultPingPeriod
+	}
+	if cfg.ReadLimit <= 0 {
+		cfg.ReadLimit = defaultReadLimit
+	}
+	return &sockets{
+		conns:    map[*wsContext]struct{}{},
+		upgrader: websocket.Upgrader{CheckOrigin: cfg.CheckOrigin},
+		cfg:      cfg,
+	}
+}
+
+// handler upgrades the request once the route middlewares accepted it.
+func (s *sockets) handler(h WebSocketHandler) HandleFunc {
+	return func(ctx IContext) error {
+		hc, ok := ctx.(httpContext)
+		if !ok {
+			return ErrWebSocketUnsupported
+		}
+
+		w, r := hc.httpRequest()
+		conn, err := s.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// the upgrader answered with an http error
+			return nil
+		}
+
+		c, cancel := context.WithCancel(ctx.Context())
+		ctx.SetContext(c)
+		ws := &wsContext{IContext: ctx, conn: conn, cancel: cancel}
+
+		s.mu.Lock()
+		s.conns[ws] = struct{}{}
+		s.mu.Unlock()
+		defer func() {
+			s.mu.Lock()
+			delete(s.conns, ws)
+			s.mu.Unlock()
+		}()
+
+		pongWait := 2 * s.cfg.PingPeriod
+		conn.SetReadLimit(s.cfg.ReadLimit)
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(pongWait))
+		})
+		go ws.ping(s.cfg.PingPeriod)
+
+		if err := h(ws); err != nil {
+			ctx.Log("websocket: " + err.Error())
+			return ws.Close(websocket.CloseInternalServerErr, "")
+		}
+		return ws.Close(websocket.CloseNormalClosure, "")
+	}
+}
+
+func (s *sockets) shutdown() {
+	s.mu.Lock()
+	conns := make([]*wsContext, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range conns {
+		c.Close(websocket.CloseGoingAway, "server shutdown")
+	}
+}
+
+// Hub broadcasts messages to the WebSocket connections that joined it.
+//
+//	hub := ms.NewHub()
+//	app.Consume("product.events", hub.Forward())
+//	app.WebSocket("/products/ws", func(ctx ms.WSContext) error {
+//		defer hub.Join(ctx)()
+//		...
+//	})
+type Hub struct {
+	mu    sync.RWMutex
+	conns map[WSContext]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{conns: map[WSContext]struct{}{}}
+}
+
+// Join adds ctx to the hub until the returned leave is called.
+func (h *Hub) Join(ctx WSContext) (leave func()) {
+	h.mu.Lock()
+	h.conns[ctx] = struct{}{}
+	h.mu.Unlock()
+
+	return func() {
+		h.mu.Lock()
+		delete(h.conns, ctx)
+		h.mu.Unlock()
+	}
+}
+
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns)
+}
+
+// Broadcast sends v to every connection, connections failing to receive it are closed.
+func (h *Hub) Broadcast(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	message := json.RawMessage(data)
+
+	h.mu.RLock()
+	conns := make([]WSContext, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	h.mu.RUnlock()
+
+	for _, c := range conns {
+		if err := c.WriteJSON(message); err != nil {
+			c.Close(websocket.CloseGoingAway, "")
+		}
+	}
+}
+
+// Forward broadcasts the messages of a Kafka consumer as is.
+func (h *Hub) Forward() ServiceHandleFunc {
+	return func(ctx IContext) error {
+		var message json.RawMessage
+		if err := ctx.ReadInput(&message); err != nil {
+			return err
+		}
+		h.Broadcast(message)
+		return nil
+	}
+}
@@ -0,0 +1,73 @@
+package ms
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebSocket(t *testing.T) {
+	hub := NewHub()
+	joined := make(chan struct{}, 1)
+
+	auth := func(next HandleFunc) HandleFunc {
+		return func(ctx IContext) error {
+			if ctx.Query("token") != "secret" {
+				return ctx.Response(http.StatusUnauthorized, ErrorResponse{Status: http.StatusUnauthorized})
+			}
+			return next(ctx)
+		}
+	}
+
+	app := newMuxServer(Config{}).(*muxApplication)
+	app.WebSocket("/ws", func(ctx WSContext) error {
+		defer hub.Join(ctx)()
+		joined <- struct{}{}
+
+		for {
+			var message map[string]string
+			err := ctx.ReadJSON(&message)
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := ctx.Response(http.StatusOK, message); err != nil {
+				return err
+			}
+		}
+	}, auth)
+
+	server := httptest.NewServer(app.mux)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+
+	_, res, err := websocket.DefaultDialer.Dial(url, nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?token=secret", nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+	<-joined
+
+	var reply map[string]string
+	assert.NoError(t, conn.WriteJSON(map[string]string{"name": "p1"}))
+	assert.NoError(t, conn.ReadJSON(&reply))
+	assert.Equal(t, "p1", reply["name"])
+
+	hub.Broadcast(map[string]string{"name": "p2"})
+	assert.NoError(t, conn.ReadJSON(&reply))
+	assert.Equal(t, "p2", reply["name"])
+
+	app.sockets.shutdown()
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway))
+}
@@ -1,14 +1,26 @@
 package repository
 
 import (
+	"context"
+
 	"github.com/sing3demons/product-service/db"
 	"github.com/sing3demons/product-service/model"
 )
 
+// ProductRepository stores the products. Called with the db.Tx of a
+// db.Transaction, its methods run in the transaction.
 type ProductRepository interface {
-	Find(findOption db.FindOption) ([]model.Product, error)
-	Create(product model.Product) error
-	FindOne(findOption db.FindOption) (model.Product, error)
+	Find(ctx context.Context, findOption db.FindOption) ([]model.Product, error)
+	// Create creates product, the result is the stored product with its id.
+	Create(ctx context.Context, product model.Product) (model.Product, error)
+	// CreateMany creates products, the result tells which ones failed.
+	CreateMany(ctx context.Context, products []model.Product) (db.BulkResult, error)
+	FindOne(ctx context.Context, findOption db.FindOption) (model.Product, error)
+	// FindAndCount finds a page of products, the result holds the total count
+	// and the page.
+	FindAndCount(ctx context.Context, findOption db.FindOption) (db.Result[[]model.Product], error)
+	// Delete deletes the product id, deleted is false when there is none.
+	Delete(ctx context.Context, id string) (deleted bool, err error)
 }
 
 type productRepository struct {
@@ -21,8 +33,8 @@ func NewProductRepository(datastore db.DataStore[model.Product]) ProductReposito
 	}
 }
 
-func (r *productRepository) Find(findOption db.FindOption) ([]model.Product, error) {
-	result := r.datastore.Find(findOption)
+func (r *productRepository) Find(ctx context.Context, findOption db.FindOption) ([]model.Product, error) {
+	result := r.datastore.Find(ctx, findOption)
 	if result.Err != nil {
 		return nil, result.Err
 	}
@@ -30,20 +42,39 @@ func (r *productRepository) Find(findOption db.FindOption) ([]model.Product, err
 	return result.Data, nil
 }
 
-func (r *productRepository) Create(product model.Product) error {
-	result := r.datastore.Create(product)
+func (r *productRepository) FindAndCount(ctx context.Context, findOption db.FindOption) (db.Result[[]model.Product], error) {
+	result := r.datastore.FindAndCount(ctx, findOption)
+	return result, result.Err
+}
+
+func (r *productRepository) Create(ctx context.Context, product model.Product) (model.Product, error) {
+	result := r.datastore.Create(ctx, product)
 	if result.Err != nil {
-		return result.Err
+		return model.Product{}, result.Err
 	}
 
-	return nil
+	return result.Data, nil
+}
+
+func (r *productRepository) CreateMany(ctx context.Context, products []model.Product) (db.BulkResult, error) {
+	result := r.datastore.CreateMany(ctx, products)
+	return result.Data, result.Err
 }
 
-func (r *productRepository) FindOne(findOption db.FindOption) (model.Product, error) {
-	result := r.datastore.FindOne(findOption)
+func (r *productRepository) FindOne(ctx context.Context, findOption db.FindOption) (model.Product, error) {
+	result := r.datastore.FindOne(ctx, findOption)
 	if result.Err != nil {
 		return model.Product{}, result.Err
 	}
 
 	return result.Data, nil
-}
\ No newline at end of file
+}
+
+func (r *productRepository) Delete(ctx context.Context, id string) (bool, error) {
+	result := r.datastore.Delete(ctx, db.Filter{Key: "id", Value: id})
+	if result.Err != nil {
+		return false, result.Err
+	}
+
+	return result.Count > 0, nil
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"context"
+
 	"github.com/sing3demons/product-service/db"
 	"github.com/sing3demons/product-service/model"
 	"github.com/stretchr/testify/mock"
@@ -14,8 +16,8 @@ func NewProductRepositoryMock() *ProductRepositoryMock {
 	return &ProductRepositoryMock{}
 }
 
-func (m *ProductRepositoryMock) Find(filter db.FindOption) ([]model.Product, error) {
-	ret := m.Called(filter)
+func (m *ProductRepositoryMock) Find(ctx context.Context, filter db.FindOption) ([]model.Product, error) {
+	ret := m.Called(ctx, filter)
 
 	var r0 []model.Product
 	if rf, ok := ret.Get(0).(func(interface{}) []model.Product); ok {
@@ -36,22 +38,31 @@ func (m *ProductRepositoryMock) Find(filter db.FindOption) ([]model.Product, err
 	return r0, r1
 }
 
-func (m *ProductRepositoryMock) Create(product model.Product) error {
-	ret := m.Called(product)
+func (m *ProductRepositoryMock) Create(ctx context.Context, product model.Product) (model.Product, error) {
+	ret := m.Called(ctx, product)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(model.Product) error); ok {
+	var r0 model.Product
+	if rf, ok := ret.Get(0).(func(model.Product) model.Product); ok {
 		r0 = rf(product)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(model.Product)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(model.Product) error); ok {
+		r1 = rf(product)
+	} else {
+		r1 = ret.Error(1)
 	}
 
-	return r0
+	return r0, r1
 }
 
 
-func (m *ProductRepositoryMock) FindOne(filter db.FindOption) (model.Product, error) {
-	ret := m.Called(filter)
+func (m *ProductRepositoryMock) FindOne(ctx context.Context, filter db.FindOption) (model.Product, error) {
+	ret := m.Called(ctx, filter)
 
 	var r0 model.Product
 	if rf, ok := ret.Get(0).(func(interface{}) model.Product); ok {
@@ -70,4 +81,68 @@ func (m *ProductRepositoryMock) FindOne(filter db.FindOption) (model.Product, er
 	}
 
 	return r0, r1
-}
\ No newline at end of file
+}
+
+func (m *ProductRepositoryMock) FindAndCount(ctx context.Context, filter db.FindOption) (db.Result[[]model.Product], error) {
+	ret := m.Called(ctx, filter)
+
+	var r0 db.Result[[]model.Product]
+	if rf, ok := ret.Get(0).(func(db.FindOption) db.Result[[]model.Product]); ok {
+		r0 = rf(filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(db.Result[[]model.Product])
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(db.FindOption) error); ok {
+		r1 = rf(filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (m *ProductRepositoryMock) Delete(ctx context.Context, id string) (bool, error) {
+	ret := m.Called(ctx, id)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Bool(0)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (m *ProductRepositoryMock) CreateMany(ctx context.Context, products []model.Product) (db.BulkResult, error) {
+	ret := m.Called(ctx, products)
+
+	var r0 db.BulkResult
+	if rf, ok := ret.Get(0).(func([]model.Product) db.BulkResult); ok {
+		r0 = rf(products)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(db.BulkResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]model.Product) error); ok {
+		r1 = rf(products)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
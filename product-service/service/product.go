@@ -1,17 +1,23 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
 
 	"github.com/sing3demons/product-service/db"
 	"github.com/sing3demons/product-service/model"
+	"github.com/sing3demons/product-service/ms"
 	"github.com/sing3demons/product-service/repository"
 )
 
 type ProductService interface {
-	Find(filter interface{}, fields interface{}) Response
-	Create(product model.Product) Response
-	FindOne(filter interface{}) ResponseOne
+	// Find lists the products of a page of options, e.g. parsed with
+	// db.QuerySchema.
+	Find(ctx context.Context, options db.FindOption) Response
+	Create(ctx context.Context, product model.Product) Response
+	FindOne(ctx context.Context, filter interface{}) ResponseOne
 }
 
 type productService struct {
@@ -29,6 +35,15 @@ type Response struct {
 	Status  int             `json:"status"`
 	Message string          `json:"message"`
 	Data    []model.Product `json:"data"`
+	// Page is set on the lists.
+	Page *Pagination `json:"page,omitempty"`
+}
+
+type Pagination struct {
+	Page       int   `json:"page"`
+	Limit      int   `json:"limit"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
 }
 
 type ResponseOne struct {
@@ -38,13 +53,13 @@ type ResponseOne struct {
 	Data    model.Product `json:"data,omitempty"`
 }
 
-func (s *productService) FindOne(filter interface{}) ResponseOne {
+func (s *productService) FindOne(ctx context.Context, filter interface{}) ResponseOne {
 	result := ResponseOne{
 		Success: false,
 		Status:  500,
 	}
 
-	product, err := s.repo.FindOne(db.FindOption{
+	product, err := s.repo.FindOne(ctx, db.FindOption{
 		Filter: []db.Filter{
 			{
 				Key:   "id",
@@ -54,8 +69,7 @@ func (s *productService) FindOne(filter interface{}) ResponseOne {
 	})
 
 	if err != nil {
-		result.Status = http.StatusNotFound
-		result.Message = err.Error()
+		result.Status, result.Message = failure(err, http.StatusNotFound)
 		return result
 	}
 
@@ -67,41 +81,20 @@ func (s *productService) FindOne(filter interface{}) ResponseOne {
 	return result
 }
 
-func (s *productService) Find(filter, fields interface{}) Response {
+func (s *productService) Find(ctx context.Context, options db.FindOption) Response {
 	result := Response{
 		Success: false,
 		Status:  500,
 	}
 
-	options := db.FindOption{}
-
-	if filter != nil {
-		if filter != "" {
-			options.Filter = []db.Filter{
-				{
-					Key:   "name",
-					Value: filter,
-				},
-			}
-		}
-	}
-
-	if fields != nil {
-		// convert fields to string
-		field, ok := fields.(string)
-		if ok {
-			options.Projection = field
-		}
-	}
-
-	products, err := s.repo.Find(options)
+	products, err := s.repo.FindAndCount(ctx, options)
 	if err != nil {
-		result.Message = err.Error()
+		result.Status, result.Message = failure(err, http.StatusInternalServerError)
 		return result
 	}
 
 	productsResponse := []model.Product{}
-	for _, product := range products {
+	for _, product := range products.Data {
 		product.Href = "/products/" + product.ID
 		productsResponse = append(productsResponse, product)
 	}
@@ -110,26 +103,52 @@ func (s *productService) Find(filter, fields interface{}) Response {
 	result.Status = 200
 	result.Message = "Success"
 	result.Data = productsResponse
+	result.Page = &Pagination{
+		Page:       products.Page,
+		Limit:      products.Limit,
+		Total:      products.Count,
+		TotalPages: products.Pages(),
+	}
 
 	return result
 }
 
-func (s *productService) Create(product model.Product) Response {
+func (s *productService) Create(ctx context.Context, product model.Product) Response {
 	result := Response{
 		Success: false,
 		Status:  500,
 	}
 
-	err := s.repo.Create(product)
+	created, err := s.repo.Create(ctx, product)
 	if err != nil {
-		result.Message = err.Error()
+		result.Status, result.Message = failure(err, http.StatusInternalServerError)
 		return result
 	}
 
 	result.Success = true
 	result.Status = http.StatusCreated
 	result.Message = "Success"
-	result.Data = []model.Product{product}
+	result.Data = []model.Product{created}
 
 	return result
 }
+
+// statusOf is the status err picks, e.g. 503 when a circuit breaker is open,
+// or fallback.
+// failure is the status and message of err, fallback for the errors without a
+// status of their own. The details of the server errors are logged, not sent.
+func failure(err error, fallback int) (int, string) {
+	status := statusOf(err, fallback)
+	if status >= http.StatusInternalServerError {
+		log.Printf("product service: %v", err)
+	}
+	return status, ms.ErrorMessage(status, err)
+}
+
+func statusOf(err error, fallback int) int {
+	var coder ms.StatusCoder
+	if errors.As(err, &coder) {
+		return coder.StatusCode()
+	}
+	return fallback
+}
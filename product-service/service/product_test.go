@@ -1,9 +1,11 @@
 package service_test
 
 import (
+	"context"
 	"net/http"
 	"testing"
 
+	"github.com/sing3demons/product-service/db"
 	"github.com/sing3demons/product-service/model"
 	"github.com/sing3demons/product-service/repository"
 	"github.com/sing3demons/product-service/service"
@@ -22,24 +24,24 @@ func TestProductServiceFindOne(t *testing.T) {
 			Quantity: 10,
 		}
 
-		productRepositoryMock.On("FindOne", mock.Anything).Return(expectedProduct, nil)
+		productRepositoryMock.On("FindOne", mock.Anything, mock.Anything).Return(expectedProduct, nil)
 
 		productService := service.NewProductService(productRepositoryMock)
 
 		filter := "1"
-		result := productService.FindOne(filter)
+		result := productService.FindOne(context.Background(), filter)
 		assert.NotNil(t, result.Data)
 	})
 
 	t.Run("Error", func(t *testing.T) {
 		productRepositoryMock := repository.NewProductRepositoryMock()
 
-		productRepositoryMock.On("FindOne", mock.Anything).Return(nil, assert.AnError)
+		productRepositoryMock.On("FindOne", mock.Anything, mock.Anything).Return(nil, assert.AnError)
 
 		productService := service.NewProductService(productRepositoryMock)
 
 		filter := "1"
-		result := productService.FindOne(filter)
+		result := productService.FindOne(context.Background(), filter)
 		assert.Equal(t, http.StatusNotFound, result.Status)
 		assert.Equal(t, false, result.Success)
 	})
@@ -56,27 +58,34 @@ func TestProductServiceFind(t *testing.T) {
 			Quantity: 10,
 		}}
 
-		productRepositoryMock.On("Find", mock.Anything).Return(expectedProducts, nil)
+		productRepositoryMock.On("FindAndCount", mock.Anything, mock.MatchedBy(func(option db.FindOption) bool {
+			return option.Page == 2 && option.Limit == 1
+		})).Return(db.Result[[]model.Product]{Data: expectedProducts, Count: 3, Page: 2, Limit: 1}, nil)
 
 		productService := service.NewProductService(productRepositoryMock)
 
-		filter := "Product"
-		fields := "name,price"
-		result := productService.Find(filter, fields)
+		result := productService.Find(context.Background(), db.FindOption{
+			Filter:     []db.Filter{{Key: "name", Operator: db.Like, Value: "Product%"}},
+			Projection: "name,price",
+			Page:       2,
+			Limit:      1,
+		})
 		assert.NotNil(t, result.Data)
+		assert.Equal(t, &service.Pagination{Page: 2, Limit: 1, Total: 3, TotalPages: 3}, result.Page)
 	})
 
 	t.Run("Error", func(t *testing.T) {
 		productRepositoryMock := repository.NewProductRepositoryMock()
 
-		productRepositoryMock.On("Find", mock.Anything).Return(nil, assert.AnError)
+		productRepositoryMock.On("FindAndCount", mock.Anything, mock.Anything).Return(nil, assert.AnError)
 
 		productService := service.NewProductService(productRepositoryMock)
 
-		filter := "Product"
-		fields := "name,price"
-		result := productService.Find(filter, fields)
+		result := productService.Find(context.Background(), db.FindOption{})
 		assert.Nil(t, result.Data)
+		assert.Equal(t, http.StatusInternalServerError, result.Status)
+		// the error of the store is logged, not sent
+		assert.Equal(t, http.StatusText(http.StatusInternalServerError), result.Message)
 	})
 }
 
@@ -91,14 +100,15 @@ func TestProductServiceCreate(t *testing.T) {
 			Quantity: 10,
 		}
 
-		productRepositoryMock.On("Create", product).Return(nil)
+		productRepositoryMock.On("Create", mock.Anything, product).Return(product, nil)
 
 		productService := service.NewProductService(productRepositoryMock)
 
-		response := productService.Create(product)
+		response := productService.Create(context.Background(), product)
 
 		assert.True(t, response.Success)
 		assert.Equal(t, http.StatusCreated, response.Status)
+		assert.Equal(t, []model.Product{product}, response.Data)
 	})
 
 	t.Run("Error", func(t *testing.T) {
@@ -106,11 +116,11 @@ func TestProductServiceCreate(t *testing.T) {
 
 		product := model.Product{}
 
-		productRepositoryMock.On("Create", mock.Anything).Return(assert.AnError)
+		productRepositoryMock.On("Create", mock.Anything, mock.Anything).Return(model.Product{}, assert.AnError)
 
 		productService := service.NewProductService(productRepositoryMock)
 
-		response := productService.Create(product)
+		response := productService.Create(context.Background(), product)
 
 		assert.False(t, response.Success)
 		assert.Equal(t, http.StatusInternalServerError, response.Status)